@@ -0,0 +1,279 @@
+package river
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+)
+
+// AccessLog configures the access log of a River.
+type AccessLog struct {
+	// Format formats log entries. Defaults to ColorLog.
+	Format AccessLogFormat
+	// Output is where logs are written. Defaults to os.Stdout.
+	Output io.Writer
+	// Fields are the fields logged by JSONLog and LogfmtLog.
+	// Defaults to all fields. See AccessLogEntry.Value for field names.
+	Fields []string
+	// SkipPaths are request paths or route patterns that are not logged.
+	// e.g. /health
+	SkipPaths []string
+	// Skip, if set, skips logging requests for which it returns true.
+	Skip func(c *Context) bool
+	// Sample is the fraction of requests logged, between 0 and 1.
+	// 0 logs all requests.
+	Sample float64
+	// Disabled disables the access log.
+	Disabled bool
+}
+
+// AccessLogFormat writes e to w. fields are the fields to log.
+type AccessLogFormat func(w io.Writer, e *AccessLogEntry, fields []string) error
+
+// AccessLogEntry is a logged request.
+type AccessLogEntry struct {
+	Time      time.Time
+	Method    string
+	Path      string
+	Route     string
+	Proto     string
+	Status    int
+	Duration  time.Duration
+	BytesIn   int64
+	BytesOut  int
+	ClientIP  string
+	UserAgent string
+	Referer   string
+	RequestID string
+}
+
+// accessLogFields are all fields of AccessLogEntry in log order.
+var accessLogFields = []string{
+	"time", "method", "path", "route", "proto", "status", "duration",
+	"bytes_in", "bytes_out", "client_ip", "user_agent", "referer", "request_id",
+}
+
+// Value returns the value of field. field is one of time, method, path,
+// route, proto, status, duration, bytes_in, bytes_out, client_ip,
+// user_agent, referer and request_id.
+func (e *AccessLogEntry) Value(field string) interface{} {
+	switch field {
+	case "time":
+		return e.Time.Format(time.RFC3339)
+	case "method":
+		return e.Method
+	case "path":
+		return e.Path
+	case "route":
+		return e.Route
+	case "proto":
+		return e.Proto
+	case "status":
+		return e.Status
+	case "duration":
+		return e.Duration.String()
+	case "bytes_in":
+		return e.BytesIn
+	case "bytes_out":
+		return e.BytesOut
+	case "client_ip":
+		return e.ClientIP
+	case "user_agent":
+		return e.UserAgent
+	case "referer":
+		return e.Referer
+	case "request_id":
+		return e.RequestID
+	}
+	return nil
+}
+
+// AccessLog sets the access log of rv. This overrules LogRequests.
+func (rv *River) AccessLog(config AccessLog) *River {
+	rv.accessLog = &config
+	rv.chainChanged()
+	return rv
+}
+
+// accessLogger returns the access log middleware of rv or nil
+// if access log is disabled.
+func (rv *River) accessLogger() Middleware {
+	switch {
+	case rv.accessLog != nil && !rv.accessLog.Disabled:
+		return accessLogger(rv.accessLog)
+	case rv.accessLog == nil:
+		// LogRequests can change after the chain is built.
+		logger := accessLogger(&AccessLog{})
+		return func(c *Context) {
+			if LogRequests {
+				logger(c)
+			} else {
+				c.Next()
+			}
+		}
+	}
+	return nil
+}
+
+var accessLogMu sync.Mutex
+
+func accessLogger(config *AccessLog) Middleware {
+	format, out, fields := config.Format, config.Output, config.Fields
+	if format == nil {
+		format = ColorLog
+	}
+	if out == nil {
+		out = os.Stdout
+	}
+	if len(fields) == 0 {
+		fields = accessLogFields
+	}
+
+	return func(c *Context) {
+		start := time.Now()
+		var body *countingReader
+		if c.Request.Body != nil {
+			body = &countingReader{ReadCloser: c.Request.Body}
+			c.Request.Body = body
+		}
+
+		c.Next()
+
+		if config.skip(c) {
+			return
+		}
+		e := &AccessLogEntry{
+			Time:      start,
+			Method:    c.Method,
+			Path:      c.URL.Path,
+			Route:     c.Route(),
+			Proto:     c.Proto,
+			Status:    c.Status(),
+			Duration:  time.Since(start),
+			BytesOut:  c.Written(),
+			ClientIP:  c.ClientIP(),
+			UserAgent: c.UserAgent(),
+			Referer:   c.Referer(),
+			RequestID: c.RequestID(),
+		}
+		if body != nil {
+			e.BytesIn = body.n
+		}
+
+		var b bytes.Buffer
+		if err := format(&b, e, fields); err != nil {
+			log.printf("Access log error: %v", err)
+			return
+		}
+		accessLogMu.Lock()
+		defer accessLogMu.Unlock()
+		b.WriteTo(out)
+	}
+}
+
+func (a *AccessLog) skip(c *Context) bool {
+	for _, p := range a.SkipPaths {
+		if p == c.URL.Path || p == c.Route() {
+			return true
+		}
+	}
+	if a.Skip != nil && a.Skip(c) {
+		return true
+	}
+	return a.Sample > 0 && a.Sample < 1 && rand.Float64() >= a.Sample
+}
+
+// ColorLog logs requests in a colourful way with a short request ID.
+// fields are ignored.
+func ColorLog(w io.Writer, e *AccessLogEntry, fields []string) error {
+	bg := color.BgBlack
+	switch {
+	case e.Status >= 200 && e.Status < 300:
+		bg = color.BgGreen
+	case e.Status >= 300 && e.Status < 400:
+		bg = color.BgBlue
+	case e.Status >= 400 && e.Status < 500:
+		bg = color.BgYellow
+	case e.Status >= 500 && e.Status < 600:
+		bg = color.BgRed
+	}
+
+	paint := color.New(bg, color.FgWhite, color.Bold).SprintFunc()
+	status := paint(fmt.Sprintf("  %d  ", e.Status))
+	size := humanize.Bytes(uint64(e.BytesOut))
+
+	id := e.RequestID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+
+	_, err := fmt.Fprintf(w, "%s%v|%s|%15v|%6s|%8s|%-4s %s\n",
+		log.prefix(),
+		e.Time.Format("2006-01-02 15:04:05"),
+		status, e.Duration, size, id, e.Method, e.Path,
+	)
+	return err
+}
+
+// CombinedLog logs requests in Apache combined log format. fields are ignored.
+func CombinedLog(w io.Writer, e *AccessLogEntry, fields []string) error {
+	_, err := fmt.Fprintf(w, "%s - - [%s] \"%s %s %s\" %d %d %q %q\n",
+		e.ClientIP,
+		e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		e.Method, e.Path, e.Proto, e.Status, e.BytesOut,
+		orDash(e.Referer), orDash(e.UserAgent),
+	)
+	return err
+}
+
+// JSONLog logs requests as JSON lines.
+func JSONLog(w io.Writer, e *AccessLogEntry, fields []string) error {
+	m := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		m[f] = e.Value(f)
+	}
+	return json.NewEncoder(w).Encode(m)
+}
+
+// LogfmtLog logs requests as logfmt key=value pairs.
+func LogfmtLog(w io.Writer, e *AccessLogEntry, fields []string) error {
+	pairs := make([]string, len(fields))
+	for i, f := range fields {
+		v := fmt.Sprint(e.Value(f))
+		if v == "" || strings.ContainsAny(v, " \"=") {
+			v = strconv.Quote(v)
+		}
+		pairs[i] = f + "=" + v
+	}
+	_, err := fmt.Fprintln(w, strings.Join(pairs, " "))
+	return err
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// countingReader counts the bytes read from the request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.ReadCloser.Read(b)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,78 @@
+package river
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLog(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEndpoint().
+		Post("/:id", func(c *Context) {
+			var v M
+			c.DecodeJSONBody(&v)
+			c.Render(201, v)
+		}).
+		Get("/health", func() string { return "ok" })
+
+	tests := []struct {
+		format AccessLogFormat
+		fields []string
+		line   string
+	}{
+		{JSONLog, []string{"route", "status", "bytes_in", "client_ip"},
+			`{"bytes_in":10,"client_ip":"10.0.0.1","route":"/user/:id","status":201}`},
+		{LogfmtLog, []string{"method", "path", "status", "user_agent"},
+			`method=POST path=/user/1 status=201 user_agent="river test"`},
+		{CombinedLog, nil, `"POST /user/1 HTTP/1.1" 201 10 "-" "river test"`},
+	}
+	for i, test := range tests {
+		b.Reset()
+		rv := New().Handle("/user", e).AccessLog(AccessLog{
+			Format:    test.format,
+			Output:    &b,
+			Fields:    test.fields,
+			SkipPaths: []string{"/user/health"},
+		})
+
+		r := httptest.NewRequest("POST", "/user/1", strings.NewReader(`{"a": "b"}`))
+		r.Header.Set("X-Forwarded-For", "10.0.0.1, 10.0.0.2")
+		r.Header.Set("User-Agent", "river test")
+		rv.ServeHTTP(httptest.NewRecorder(), r)
+		rv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/health", nil))
+
+		line := strings.TrimSpace(b.String())
+		if i == 0 {
+			var m M
+			json.Unmarshal(b.Bytes(), &m)
+			out, _ := json.Marshal(m)
+			line = string(out)
+		}
+		if !strings.Contains(line, test.line) {
+			t.Errorf("Test %d: expected %s in log, found %s", i, test.line, line)
+		}
+		if strings.Count(b.String(), "\n") != 1 {
+			t.Errorf("Test %d: expected 1 line, found %q", i, b.String())
+		}
+	}
+}
+
+func TestColorLog(t *testing.T) {
+	var b bytes.Buffer
+	e := &AccessLogEntry{
+		Time:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Method: "GET",
+		Path:   "/user",
+		Status: 200,
+	}
+	if err := ColorLog(&b, e, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "2020-01-02 03:04:05") {
+		t.Errorf("expected entry time in log, found %q", b.String())
+	}
+}
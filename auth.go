@@ -0,0 +1,65 @@
+package river
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Principal is an authenticated client. It is registered by the
+// authentication middlewares and can be a handler parameter.
+//  func(p river.Principal) {...}
+type Principal struct {
+	// Subject identifies the client e.g. username or JWT subject.
+	Subject string
+	// Scheme is the authentication scheme; Basic, APIKey or Bearer.
+	Scheme string
+	// Claims are the claims of a JWT.
+	Claims map[string]interface{}
+}
+
+// BasicAuth creates a middleware for HTTP Basic authentication.
+// validate reports whether the username and password are valid.
+func BasicAuth(realm string, validate func(username, password string) bool) Middleware {
+	challenge := fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", realm)
+	return func(c *Context) {
+		username, password, ok := c.Request.BasicAuth()
+		if !ok || !validate(username, password) {
+			unauthorized(c, challenge, "Invalid credentials")
+			return
+		}
+		c.Register(Principal{Subject: username, Scheme: "Basic"})
+		c.Next()
+	}
+}
+
+// APIKeyAuth creates a middleware for API key authentication with the
+// key in header. validate returns the subject of a valid key.
+//  river.APIKeyAuth("X-API-Key", func(key string) (string, bool) {...})
+func APIKeyAuth(header string, validate func(key string) (subject string, ok bool)) Middleware {
+	return func(c *Context) {
+		key := c.Request.Header.Get(header)
+		subject, ok := "", false
+		if key != "" {
+			subject, ok = validate(key)
+		}
+		if !ok {
+			unauthorized(c, "", "Invalid API key")
+			return
+		}
+		c.Register(Principal{Subject: subject, Scheme: "APIKey"})
+		c.Next()
+	}
+}
+
+// unauthorized renders an Error with status 401 and sets the
+// WWW-Authenticate header to challenge, if not empty.
+func unauthorized(c *Context, challenge, detail string) {
+	if challenge != "" {
+		c.Header().Set("WWW-Authenticate", challenge)
+	}
+	c.Render(http.StatusUnauthorized, &Error{
+		Status:    http.StatusUnauthorized,
+		Detail:    detail,
+		RequestID: c.RequestID(),
+	})
+}
@@ -0,0 +1,151 @@
+package river
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func authRiver(auth Middleware, principal *Principal) *River {
+	e := NewEndpoint().Get("/", func(p Principal) { *principal = p })
+	e.Use(auth)
+	return New().Handle("/", e)
+}
+
+func TestBasicAndAPIKeyAuth(t *testing.T) {
+	var p Principal
+	basic := authRiver(BasicAuth("test", func(u, pw string) bool { return u == "ada" && pw == "secret" }), &p)
+	apiKey := authRiver(APIKeyAuth("X-API-Key", func(key string) (string, bool) { return "svc", key == "k1" }), &p)
+
+	tests := []struct {
+		rv              *River
+		header, value   string
+		status          int
+		subject, scheme string
+	}{
+		{basic, "Authorization", "Basic " + base64.StdEncoding.EncodeToString([]byte("ada:secret")), 200, "ada", "Basic"},
+		{basic, "Authorization", "Basic " + base64.StdEncoding.EncodeToString([]byte("ada:wrong")), 401, "", ""},
+		{basic, "", "", 401, "", ""},
+		{apiKey, "X-API-Key", "k1", 200, "svc", "APIKey"},
+		{apiKey, "X-API-Key", "k2", 401, "", ""},
+	}
+	for i, test := range tests {
+		p = Principal{}
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.header != "" {
+			r.Header.Set(test.header, test.value)
+		}
+		w := httptest.NewRecorder()
+		test.rv.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("Test %d: expected status %d, found %d", i, test.status, w.Code)
+		}
+		if p.Subject != test.subject || p.Scheme != test.scheme {
+			t.Errorf("Test %d: expected %s %s, found %+v", i, test.scheme, test.subject, p)
+		}
+	}
+	w := httptest.NewRecorder()
+	basic.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if h := w.Header().Get("WWW-Authenticate"); h != `Basic realm="test", charset="UTF-8"` {
+		t.Errorf("Expected Basic challenge, found %s", h)
+	}
+}
+
+func signJWT(alg, kid string, claims map[string]interface{}, key interface{}) string {
+	enc := func(v interface{}) string {
+		b, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := enc(map[string]string{"alg": alg, "typ": "JWT", "kid": kid}) + "." + enc(claims)
+	hash := sha256.Sum256([]byte(signed))
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sig, _ = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, hash[:])
+	case *ecdsa.PrivateKey:
+		r, s, _ := ecdsa.Sign(rand.Reader, k, hash[:])
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWTAuth(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"EC","crv":"P-256","kid":"ec1","x":%q,"y":%q}]}`,
+		b64(ecKey.X.Bytes()), b64(ecKey.Y.Bytes()))
+	dir, _ := ioutil.TempDir("", "river")
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "jwks.json")
+	ioutil.WriteFile(file, []byte(jwks), 0600)
+
+	var p Principal
+	hs := authRiver(JWTAuth(JWTConfig{Secret: secret, Audience: "api"}), &p)
+	rs := authRiver(JWTAuth(JWTConfig{PublicKey: &rsaKey.PublicKey}), &p)
+	es := authRiver(JWTAuth(JWTConfig{JWKSFile: file}), &p)
+	noExp := authRiver(JWTAuth(JWTConfig{Secret: secret, AllowNoExpiry: true}), &p)
+
+	now := time.Now().Unix()
+	valid := map[string]interface{}{"sub": "ada", "aud": []string{"api"}, "exp": now + 60}
+	tests := []struct {
+		rv     *River
+		token  string
+		status int
+	}{
+		{hs, signJWT("HS256", "", valid, secret), 200},
+		{hs, signJWT("HS256", "", valid, []byte("wrong")), 401},
+		{hs, signJWT("HS256", "", map[string]interface{}{"sub": "ada", "aud": "other", "exp": now + 60}, secret), 401},
+		{hs, signJWT("HS256", "", map[string]interface{}{"sub": "ada", "aud": "api"}, secret), 401},
+		{hs, signJWT("HS256", "", map[string]interface{}{"sub": "ada", "aud": "api", "exp": "never"}, secret), 401},
+		{noExp, signJWT("HS256", "", map[string]interface{}{"sub": "ada"}, secret), 200},
+		{noExp, signJWT("HS256", "", map[string]interface{}{"sub": "ada", "exp": "never"}, secret), 401},
+		{hs, signJWT("HS256", "", map[string]interface{}{"sub": "ada", "aud": "api", "exp": now - 60}, secret), 401},
+		{hs, signJWT("HS256", "", map[string]interface{}{"sub": "ada", "aud": "api", "exp": now + 120, "nbf": now + 60}, secret), 401},
+		{rs, signJWT("RS256", "", valid, rsaKey), 200},
+		{rs, signJWT("HS256", "", valid, secret), 401},
+		{es, signJWT("ES256", "ec1", valid, ecKey), 200},
+		{es, signJWT("ES256", "unknown", valid, ecKey), 401},
+		{hs, "not.a.token", 401},
+		{hs, "", 401},
+	}
+	for i, test := range tests {
+		p = Principal{}
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.token != "" {
+			r.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		test.rv.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("Test %d: expected status %d, found %d: %s", i, test.status, w.Code, w.Body)
+		}
+		if test.status == 200 && (p.Subject != "ada" || p.Scheme != "Bearer" || p.Claims["sub"] != "ada") {
+			t.Errorf("Test %d: expected principal ada, found %+v", i, p)
+		}
+		if test.status == 401 && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("Test %d: expected WWW-Authenticate header", i)
+		}
+	}
+}
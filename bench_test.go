@@ -0,0 +1,71 @@
+package river
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// benchWriter is a ResponseWriter that discards responses.
+type benchWriter struct {
+	header http.Header
+}
+
+func (w *benchWriter) Header() http.Header         { return w.header }
+func (w *benchWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *benchWriter) WriteHeader(int)             {}
+
+type benchService struct{}
+
+type benchRequest struct {
+	ID string `river:"path=id"`
+}
+
+func benchmark(b *testing.B, h http.Handler) {
+	r := httptest.NewRequest("GET", "/user/1", nil)
+	r.Header.Set(RequestIDHeader, "bench")
+	w := &benchWriter{header: http.Header{}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(w, r)
+	}
+}
+
+func benchmarkRiver(b *testing.B, h Handler) {
+	e := NewEndpoint().Get("/:id", h)
+	e.Register(&benchService{})
+	benchmark(b, New().AccessLog(AccessLog{Disabled: true}).Handle("/user", e))
+}
+
+func BenchmarkHTTPRouter(b *testing.B) {
+	r := httprouter.New()
+	r.GET("/user/:id", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.WriteHeader(200)
+	})
+	benchmark(b, r)
+}
+
+func BenchmarkRiver_Context(b *testing.B) {
+	benchmarkRiver(b, func(c *Context) { c.WriteHeader(200) })
+}
+
+func BenchmarkRiver_HandlerFunc(b *testing.B) {
+	benchmarkRiver(b, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(200) })
+}
+
+func BenchmarkRiver_Injected(b *testing.B) {
+	benchmarkRiver(b, func(c *Context, s *benchService, id RequestID) { c.WriteHeader(200) })
+}
+
+func BenchmarkRiver_Binding(b *testing.B) {
+	benchmarkRiver(b, func(req benchRequest) error { return nil })
+}
+
+func BenchmarkRiver_Provided(b *testing.B) {
+	e := NewEndpoint().Get("/:id", func(c *Context, s *benchService) { c.WriteHeader(200) })
+	e.Provide(func() *benchService { return &benchService{} }, RequestScope)
+	benchmark(b, New().AccessLog(AccessLog{Disabled: true}).Handle("/user", e))
+}
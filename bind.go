@@ -0,0 +1,211 @@
+package river
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// BindError is the error returned when a request value cannot
+// be bound to a struct field.
+type BindError struct {
+	// Source is one of path, query, header or body.
+	Source string
+	// Name is the name of the request value.
+	Name string
+	// Field is the struct field.
+	Field string
+	Err   error
+}
+
+func (b *BindError) Error() string {
+	if b.Source == "body" {
+		return fmt.Sprintf("invalid body for %s: %v", b.Field, b.Err)
+	}
+	return fmt.Sprintf("invalid %s parameter %q: %v", b.Source, b.Name, b.Err)
+}
+
+// Unwrap returns the underlying error.
+func (b *BindError) Unwrap() error {
+	return b.Err
+}
+
+// Bind fills the fields of the struct pointed to by v from the request.
+// Fields are selected with the river tag.
+//  type Request struct {
+//    ID    string `river:"path=id"`
+//    Limit int    `river:"query=limit"`
+//    Token string `river:"header=X-Token"`
+//    User  User   `river:"body"`
+//  }
+// Body fields are decoded with DecodeJSONBody. Other fields can be strings,
+// booleans, numbers, encoding.TextUnmarshaler, pointers or slices of those.
+// A missing request value leaves the field unchanged.
+//
+// Handler parameters of struct types with river tags are bound automatically
+// and the request fails with 400 if binding fails, or with the status of
+// an Error from reading the body e.g. 413 for a body over the limit.
+func (c *Context) Bind(v interface{}) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot bind to %T, must be pointer to struct", v)
+	}
+	b, _ := bindingOf(val.Elem().Type())
+	return b.bind(c, val.Elem())
+}
+
+type bindField struct {
+	index        int
+	field        string
+	source, name string
+}
+
+// binding is the river tagged fields of a struct type.
+type binding struct {
+	typ    reflect.Type
+	fields []bindField
+}
+
+// bindingOf returns the binding of t. t must be a struct or
+// pointer to struct with river tags to be bindable.
+func bindingOf(t reflect.Type) (*binding, bool) {
+	b := &binding{typ: t}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return b, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("river")
+		if !ok || f.PkgPath != "" {
+			continue
+		}
+		source, name := tag, f.Name
+		if j := strings.Index(tag, "="); j > -1 {
+			source, name = tag[:j], tag[j+1:]
+		}
+		switch source {
+		case "path", "query", "header", "body":
+		default:
+			panic(fmt.Sprintf("invalid river tag %q on %s.%s", tag, t.Name(), f.Name))
+		}
+		b.fields = append(b.fields, bindField{index: i, field: f.Name, source: source, name: name})
+	}
+	return b, len(b.fields) > 0
+}
+
+// bindingsOf returns the bindings for the parameters of function type t.
+func bindingsOf(t reflect.Type) []*binding {
+	var bindings []*binding
+	for i := 0; i < t.NumIn(); i++ {
+		if b, ok := bindingOf(t.In(i)); ok {
+			bindings = append(bindings, b)
+		}
+	}
+	return bindings
+}
+
+// value creates a new value of the binding type from the request.
+func (b *binding) value(c *Context) (reflect.Value, error) {
+	t := b.typ
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	v := reflect.New(t)
+	if err := b.bind(c, v.Elem()); err != nil {
+		return v, err
+	}
+	if b.typ.Kind() == reflect.Ptr {
+		return v, nil
+	}
+	return v.Elem(), nil
+}
+
+func (b *binding) bind(c *Context, v reflect.Value) error {
+	for _, f := range b.fields {
+		field := v.Field(f.index)
+		var values []string
+		switch f.source {
+		case "body":
+			if err := c.DecodeJSONBody(field.Addr().Interface()); err != nil {
+				return &BindError{Source: f.source, Name: f.name, Field: f.field, Err: err}
+			}
+			continue
+		case "path":
+			if value := c.Param(f.name); value != "" {
+				values = []string{value}
+			}
+		case "query":
+			values = c.URL.Query()[f.name]
+		case "header":
+			values = c.Request.Header[http.CanonicalHeaderKey(f.name)]
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if err := setValue(field, values); err != nil {
+			return &BindError{Source: f.source, Name: f.name, Field: f.field, Err: err}
+		}
+	}
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// setValue converts values to the type of v and stores it in v.
+func setValue(v reflect.Value, values []string) error {
+	if reflect.PtrTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(values[0]))
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), values); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i := range values {
+			if err := setValue(slice.Index(i), values[i:i+1]); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.String:
+		v.SetString(values[0])
+	case reflect.Bool:
+		b, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(values[0], 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(values[0], 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(values[0], v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
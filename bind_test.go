@@ -0,0 +1,42 @@
+package river
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	type item struct{ Name string }
+	type request struct {
+		ID    string   `river:"path=id"`
+		Limit *int     `river:"query=limit"`
+		Tags  []string `river:"query=tag"`
+		Token string   `river:"header=X-Token"`
+		Item  item     `river:"body"`
+	}
+
+	var req request
+	e := NewEndpoint().Put("/:id", func(r request) {
+		req = r
+	})
+	rv := New().Handle("/item", e)
+
+	r := httptest.NewRequest("PUT", "/item/12?limit=5&tag=a&tag=b", strings.NewReader(`{"name": "river"}`))
+	r.Header.Set("X-Token", "secret")
+	rv.ServeHTTP(httptest.NewRecorder(), r)
+
+	if req.ID != "12" || req.Limit == nil || *req.Limit != 5 || req.Token != "secret" || req.Item.Name != "river" {
+		t.Errorf("unexpected binding %+v", req)
+	}
+	if len(req.Tags) != 2 || req.Tags[1] != "b" {
+		t.Errorf("expected tags [a b], found %v", req.Tags)
+	}
+
+	w := httptest.NewRecorder()
+	rv.ServeHTTP(w, httptest.NewRequest("PUT", "/item/12?limit=five", strings.NewReader(`{}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, found %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,199 @@
+package river
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ETagMode is how ETags are computed for rendered responses.
+type ETagMode int
+
+// ETag modes.
+const (
+	// NoETag disables ETags.
+	NoETag ETagMode = iota
+	// StrongETag computes strong ETags from the rendered body.
+	StrongETag
+	// WeakETag computes weak ETags from the rendered body.
+	WeakETag
+)
+
+// CachePolicy is an HTTP caching policy.
+//  river.CachePolicy{Public: true, MaxAge: time.Hour, ETag: river.WeakETag}
+type CachePolicy struct {
+	// MaxAge is max-age, how long responses are fresh.
+	MaxAge time.Duration
+	// SharedMaxAge is s-maxage, max-age for shared caches e.g. CDNs.
+	SharedMaxAge time.Duration
+	// Public allows shared caches to store responses.
+	Public bool
+	// Private restricts storing responses to the client.
+	Private bool
+	// NoCache requires revalidation before using a stored response.
+	NoCache bool
+	// NoStore disallows storing responses.
+	NoStore bool
+	// MustRevalidate disallows using stale responses.
+	MustRevalidate bool
+	// Immutable indicates responses never change while fresh.
+	Immutable bool
+	// ETag is how ETags are computed for rendered responses.
+	ETag ETagMode
+}
+
+// String returns the Cache-Control header value of p.
+func (p CachePolicy) String() string {
+	var directives []string
+	add := func(ok bool, directive string) {
+		if ok {
+			directives = append(directives, directive)
+		}
+	}
+	add(p.Public, "public")
+	add(p.Private, "private")
+	add(p.NoCache, "no-cache")
+	add(p.NoStore, "no-store")
+	add(p.MaxAge > 0, "max-age="+strconv.Itoa(int(p.MaxAge/time.Second)))
+	add(p.SharedMaxAge > 0, "s-maxage="+strconv.Itoa(int(p.SharedMaxAge/time.Second)))
+	add(p.MustRevalidate, "must-revalidate")
+	add(p.Immutable, "immutable")
+	return strings.Join(directives, ", ")
+}
+
+// CacheControl sets the caching policy for all endpoints.
+// An endpoint policy overrules this.
+func (rv *River) CacheControl(policy CachePolicy) *River {
+	rv.cache = &policy
+	return rv
+}
+
+// CacheControl sets the caching policy for the endpoint and endpoints
+// mounted on it. The Cache-Control header is set for responses with
+// status below 400, unless set by the handler.
+func (e *Endpoint) CacheControl(policy CachePolicy) *Endpoint {
+	e.cache = &policy
+	return e
+}
+
+// cachePolicy returns the caching policy for e.
+func (rv *River) cachePolicy(e *Endpoint) *CachePolicy {
+	for p := e; p != nil; p = p.parent {
+		if p.cache != nil {
+			return p.cache
+		}
+	}
+	return rv.cache
+}
+
+// CacheControl sets the Cache-Control header of the response to policy
+// and the ETag mode for subsequent calls to Render.
+func (c *Context) CacheControl(policy CachePolicy) {
+	c.cache = &policy
+	c.Header().Set("Cache-Control", policy.String())
+}
+
+// LastModified sets the Last-Modified header of the response.
+// Render responds with 304 if the If-Modified-Since header of
+// the request is not before t.
+func (c *Context) LastModified(t time.Time) {
+	c.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// setCacheControl sets the Cache-Control header from the caching
+// policy for successful responses, if not set.
+func (c *Context) setCacheControl(status int) {
+	if c.cache != nil && status < 400 && c.Header().Get("Cache-Control") == "" {
+		if v := c.cache.String(); v != "" {
+			c.Header().Set("Cache-Control", v)
+		}
+	}
+}
+
+// conditional checks if a response with status can be 304.
+func (c *Context) conditional(status int) bool {
+	return (c.Method == "GET" || c.Method == "HEAD") && status == http.StatusOK
+}
+
+// notModified checks the conditional headers of the request
+// against ETag and Last-Modified headers of the response.
+func (c *Context) notModified() bool {
+	if match := c.Request.Header.Get("If-None-Match"); match != "" {
+		etag := c.Header().Get("ETag")
+		return etag != "" && etagMatch(match, etag)
+	}
+	since, err := http.ParseTime(c.Request.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(c.Header().Get("Last-Modified"))
+	return err == nil && !modified.After(since)
+}
+
+// renderCached renders data to a buffer to compute its ETag and
+// responds with 304 if the request's ETag matches.
+func (c *Context) renderCached(status int, renderer Renderer, data interface{}) error {
+	rw := c.rw
+	b := &bufferWriter{rw: rw}
+	c.rw = b
+	err := renderer(c, data)
+	c.rw, c.headerWritten, c.status, c.written = rw, false, 0, 0
+
+	if b.status == 0 {
+		b.status = status
+	}
+	if c.conditional(b.status) && c.Header().Get("ETag") == "" && err == nil {
+		sum := sha256.Sum256(b.buf.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		if c.cache.ETag == WeakETag {
+			etag = "W/" + etag
+		}
+		c.Header().Set("ETag", etag)
+		if c.notModified() {
+			c.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+	c.WriteHeader(b.status)
+	if _, werr := c.Write(b.buf.Bytes()); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// etagMatch compares etag to an If-None-Match header with weak comparison.
+func etagMatch(match, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, m := range strings.Split(match, ",") {
+		m = strings.TrimSpace(m)
+		if m == "*" || strings.TrimPrefix(m, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferWriter buffers a response.
+type bufferWriter struct {
+	rw     http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+func (b *bufferWriter) Header() http.Header {
+	return b.rw.Header()
+}
+
+func (b *bufferWriter) Write(p []byte) (int, error) {
+	return b.buf.Write(p)
+}
+
+func (b *bufferWriter) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
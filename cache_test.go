@@ -0,0 +1,88 @@
+package river
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachePolicy(t *testing.T) {
+	tests := []struct {
+		policy   CachePolicy
+		expected string
+	}{
+		{CachePolicy{Public: true, MaxAge: time.Hour}, "public, max-age=3600"},
+		{CachePolicy{Private: true, NoCache: true, MustRevalidate: true}, "private, no-cache, must-revalidate"},
+		{CachePolicy{NoStore: true}, "no-store"},
+		{CachePolicy{Public: true, MaxAge: time.Minute, SharedMaxAge: time.Hour, Immutable: true}, "public, max-age=60, s-maxage=3600, immutable"},
+		{CachePolicy{}, ""},
+	}
+	for i, test := range tests {
+		if s := test.policy.String(); s != test.expected {
+			t.Errorf("Test %d: expected %q, found %q", i, test.expected, s)
+		}
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	modified := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := NewEndpoint().
+		Get("/", func(c *Context) { c.Render(200, M{"name": "river"}) }).
+		Get("/missing", func(c *Context) { c.Render(404, NewError(404, "missing")) }).
+		Get("/private", func(c *Context) {
+			c.CacheControl(CachePolicy{Private: true, ETag: StrongETag})
+			c.Render(200, "private")
+		}).
+		Get("/modified", func(c *Context) {
+			c.LastModified(modified)
+			c.Render(200, "modified")
+		}).
+		Post("/", func(c *Context) { c.Render(200, "posted") })
+	rv := New().Handle("/", e.CacheControl(CachePolicy{Public: true, MaxAge: time.Minute, ETag: WeakETag}))
+
+	w := httptest.NewRecorder()
+	rv.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	etag := w.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("Expected weak ETag, found %q", etag)
+	}
+
+	tests := []struct {
+		method, path, header, value string
+		status                      int
+		cacheControl                string
+		etag                        bool
+	}{
+		{"GET", "/", "", "", 200, "public, max-age=60", true},
+		{"GET", "/", "If-None-Match", etag, 304, "public, max-age=60", true},
+		{"GET", "/", "If-None-Match", `"other", ` + strings.TrimPrefix(etag, "W/"), 304, "public, max-age=60", true},
+		{"GET", "/", "If-None-Match", `"other"`, 200, "public, max-age=60", true},
+		{"POST", "/", "If-None-Match", "*", 200, "public, max-age=60", false},
+		{"GET", "/missing", "", "", 404, "", false},
+		{"GET", "/private", "", "", 200, "private", true},
+		{"GET", "/modified", "If-Modified-Since", modified.Add(time.Hour).Format(time.RFC1123), 304, "public, max-age=60", false},
+		{"GET", "/modified", "If-Modified-Since", modified.Add(-time.Hour).Format(time.RFC1123), 200, "public, max-age=60", true},
+	}
+	for i, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if test.header != "" {
+			r.Header.Set(test.header, strings.Replace(test.value, "UTC", "GMT", 1))
+		}
+		w := httptest.NewRecorder()
+		rv.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("Test %d: expected status %d, found %d", i, test.status, w.Code)
+		}
+		if cc := w.Header().Get("Cache-Control"); cc != test.cacheControl {
+			t.Errorf("Test %d: expected Cache-Control %q, found %q", i, test.cacheControl, cc)
+		}
+		if (w.Header().Get("ETag") != "") != test.etag {
+			t.Errorf("Test %d: expected ETag %v, found %q", i, test.etag, w.Header().Get("ETag"))
+		}
+		if test.status == 304 && w.Body.Len() > 0 {
+			t.Errorf("Test %d: expected empty body, found %q", i, w.Body)
+		}
+	}
+}
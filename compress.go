@@ -0,0 +1,311 @@
+package river
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Compression configures the Compress middleware.
+type Compression struct {
+	// Level is the compression level, 1 (fastest) to 9 (best).
+	// Defaults to the default level of compress/flate.
+	Level int
+	// MinSize is the minimum response size to compress. Defaults to 1024.
+	MinSize int
+	// SkipTypes are content types that are not compressed, in addition
+	// to images, audio, video, archives and event streams.
+	SkipTypes []string
+	// MaxBodySize is the maximum size of decompressed request bodies.
+	// Defaults to 10MB.
+	MaxBodySize int64
+}
+
+// skipCompressTypes are content types or prefixes that are already
+// compressed or are streamed.
+var skipCompressTypes = []string{
+	"image/", "audio/", "video/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/x-bzip2", "application/x-7z-compressed", "application/pdf",
+	"text/event-stream",
+}
+
+// Compress creates a compression middleware. Responses are compressed
+// with gzip or deflate based on Accept-Encoding. Request bodies with
+// gzip or deflate Content-Encoding are decompressed.
+//  rv.Use(river.Compress(river.Compression{}))
+func Compress(config Compression) Middleware {
+	if config.Level == 0 {
+		config.Level = flate.DefaultCompression
+	}
+	if config.Level < flate.HuffmanOnly || config.Level > flate.BestCompression {
+		panic(fmt.Sprintf("river: invalid compression level %d", config.Level))
+	}
+	if config.MinSize == 0 {
+		config.MinSize = 1024
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = 10 << 20
+	}
+	skipTypes := append(skipCompressTypes, config.SkipTypes...)
+
+	return func(c *Context) {
+		if err := decompressBody(c, config.MaxBodySize); err != nil {
+			c.Render(statusOf(err), err)
+			return
+		}
+
+		addVary(c.Header(), "Accept-Encoding")
+		encoding := acceptEncoding(c.Request.Header.Get("Accept-Encoding"))
+		if encoding == "" || c.Method == "HEAD" {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{
+			rw:        c.rw,
+			encoding:  encoding,
+			level:     config.Level,
+			minSize:   config.MinSize,
+			skipTypes: skipTypes,
+		}
+		c.rw = cw
+		defer func() {
+			cw.close()
+			c.rw = cw.rw
+			c.written = cw.written
+		}()
+		c.Next()
+	}
+}
+
+// acceptEncoding returns the preferred of gzip and deflate
+// in Accept-Encoding or empty string if neither is accepted.
+func acceptEncoding(accept string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name == "" {
+			continue
+		}
+		q[name] = 1
+		for _, p := range params[1:] {
+			if p = strings.TrimSpace(p); strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q[name] = v
+				}
+			}
+		}
+	}
+	for _, name := range []string{"gzip", "deflate"} {
+		if _, ok := q[name]; !ok {
+			q[name] = q["*"]
+		}
+	}
+	switch {
+	case q["gzip"] > 0 && q["gzip"] >= q["deflate"]:
+		return "gzip"
+	case q["deflate"] > 0:
+		return "deflate"
+	}
+	return ""
+}
+
+// decompressBody replaces the request body of c with a decompressing
+// reader limited to limit bytes, if the body is compressed.
+func decompressBody(c *Context, limit int64) error {
+	encoding := strings.ToLower(strings.TrimSpace(c.Request.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" || c.Request.Body == nil {
+		return nil
+	}
+
+	var r io.ReadCloser
+	switch encoding {
+	case "gzip", "x-gzip":
+		gr, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			return NewError(http.StatusBadRequest, "Invalid gzip request body")
+		}
+		r = gr
+	case "deflate":
+		r = flate.NewReader(c.Request.Body)
+	default:
+		return NewError(http.StatusUnsupportedMediaType, "Unsupported Content-Encoding "+encoding)
+	}
+
+	c.Request.Body = &limitedBody{r: r, body: c.Request.Body, n: limit}
+	c.Request.Header.Del("Content-Encoding")
+	c.Request.Header.Del("Content-Length")
+	c.Request.ContentLength = -1
+	return nil
+}
+
+// limitedBody is a decompressed request body that errors after n bytes.
+type limitedBody struct {
+	r    io.ReadCloser
+	body io.Closer
+	n    int64
+}
+
+func (l *limitedBody) Read(b []byte) (int, error) {
+	if l.n <= 0 {
+		// check if there is more to read.
+		if n, _ := l.r.Read(make([]byte, 1)); n > 0 {
+			return 0, NewError(http.StatusRequestEntityTooLarge, "Request body too large")
+		}
+		return 0, io.EOF
+	}
+	if int64(len(b)) > l.n {
+		b = b[:l.n]
+	}
+	n, err := l.r.Read(b)
+	l.n -= int64(n)
+	return n, err
+}
+
+func (l *limitedBody) Close() error {
+	l.r.Close()
+	return l.body.Close()
+}
+
+// compressWriter compresses responses. The first minSize bytes are
+// buffered to decide if the response should be compressed.
+type compressWriter struct {
+	rw        http.ResponseWriter
+	encoding  string
+	level     int
+	minSize   int
+	skipTypes []string
+
+	status  int
+	buf     bytes.Buffer
+	decided bool
+	w       io.WriteCloser
+	written int
+}
+
+func (cw *compressWriter) Header() http.Header {
+	return cw.rw.Header()
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.decided {
+		return cw.write(b)
+	}
+	cw.buf.Write(b)
+	if cw.buf.Len() >= cw.minSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends buffered data to the client. It implements http.Flusher.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(cw.buf.Len() > 0)
+	}
+	if f, ok := cw.w.(interface {
+		Flush() error
+	}); ok {
+		f.Flush()
+	}
+	if f, ok := cw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// decide writes the header and buffered data, compressing
+// if compress is true and the content type is compressible.
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+	h := cw.rw.Header()
+	if h.Get("Content-Type") == "" && cw.buf.Len() > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf.Bytes()))
+	}
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	compress = compress && h.Get("Content-Encoding") == "" && cw.compressible(h.Get("Content-Type")) &&
+		cw.status != http.StatusNoContent && cw.status != http.StatusNotModified
+
+	if compress {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", cw.encoding)
+		if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			// compressed body differs from the one the ETag is for.
+			h.Set("ETag", "W/"+etag)
+		}
+		if cw.encoding == "gzip" {
+			cw.w, _ = gzip.NewWriterLevel(countWriter{cw}, cw.level)
+		} else {
+			cw.w, _ = flate.NewWriter(countWriter{cw}, cw.level)
+		}
+	}
+	cw.rw.WriteHeader(cw.status)
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+	_, err := cw.write(cw.buf.Bytes())
+	cw.buf.Reset()
+	return err
+}
+
+func (cw *compressWriter) write(b []byte) (int, error) {
+	if cw.w != nil {
+		return cw.w.Write(b)
+	}
+	return countWriter{cw}.Write(b)
+}
+
+func (cw *compressWriter) compressible(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, t := range cw.skipTypes {
+		if strings.HasPrefix(contentType, t) {
+			return strings.HasPrefix(contentType, "image/svg")
+		}
+	}
+	return true
+}
+
+// close writes any buffered data and completes the compressed stream.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 && cw.buf.Len() == 0 {
+			// nothing written, let the next writer handle it.
+			return
+		}
+		cw.decide(false)
+	}
+	if cw.w != nil {
+		cw.w.Close()
+	}
+}
+
+// countWriter writes to the underlying ResponseWriter,
+// counting written bytes.
+type countWriter struct {
+	cw *compressWriter
+}
+
+func (w countWriter) Write(b []byte) (int, error) {
+	n, err := w.cw.rw.Write(b)
+	w.cw.written += n
+	return n, err
+}
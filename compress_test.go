@@ -0,0 +1,128 @@
+package river
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("river ", 500)
+	var status, written int
+	e := NewEndpoint().
+		Get("/large", func(c *Context) { c.Render(200, large) }).
+		Get("/small", func(c *Context) { c.Render(200, "small") }).
+		Get("/image", func(c *Context) {
+			c.Header().Set("Content-Type", "image/png")
+			c.Write([]byte(large))
+		}).
+		Get("/empty", func(c *Context) { c.WriteHeader(204) })
+	rv := New().Renderer(PlainRenderer).Handle("/", e)
+	rv.Use(func(c *Context) {
+		c.Next()
+		status, written = c.Status(), c.Written()
+	})
+	rv.Use(Compress(Compression{}))
+
+	tests := []struct {
+		path, accept, encoding string
+		status                 int
+	}{
+		{"/large", "gzip, deflate", "gzip", 200},
+		{"/large", "deflate, gzip;q=0.5", "deflate", 200},
+		{"/large", "br", "", 200},
+		{"/large", "", "", 200},
+		{"/small", "gzip", "", 200},
+		{"/image", "gzip", "", 200},
+		{"/empty", "gzip", "", 204},
+	}
+	for i, test := range tests {
+		r := httptest.NewRequest("GET", test.path, nil)
+		r.Header.Set("Accept-Encoding", test.accept)
+		w := httptest.NewRecorder()
+		rv.ServeHTTP(w, r)
+
+		if enc := w.Header().Get("Content-Encoding"); enc != test.encoding {
+			t.Errorf("Test %d: expected encoding %q, found %q", i, test.encoding, enc)
+		}
+		if !strings.Contains(w.Header().Get("Vary"), "Accept-Encoding") {
+			t.Errorf("Test %d: expected Vary Accept-Encoding, found %q", i, w.Header().Get("Vary"))
+		}
+		if w.Code != test.status || status != test.status {
+			t.Errorf("Test %d: expected status %d, found %d and %d", i, test.status, w.Code, status)
+		}
+		if written != w.Body.Len() {
+			t.Errorf("Test %d: expected written %d, found %d", i, w.Body.Len(), written)
+		}
+
+		body := w.Body.Bytes()
+		switch test.encoding {
+		case "gzip":
+			gr, _ := gzip.NewReader(w.Body)
+			body, _ = ioutil.ReadAll(gr)
+		case "deflate":
+			body, _ = ioutil.ReadAll(flate.NewReader(w.Body))
+		}
+		if test.path == "/large" && string(body) != large {
+			t.Errorf("Test %d: expected decompressed body, found %d bytes", i, len(body))
+		}
+	}
+}
+
+func TestCompressRequestBody(t *testing.T) {
+	var decoded map[string]string
+	e := NewEndpoint().Post("/", func(c *Context) {
+		if err := c.DecodeJSONBody(&decoded); err != nil {
+			c.Render(statusOf(err), err)
+			return
+		}
+		c.RenderEmpty(200)
+	}).Put("/", func(r struct {
+		Body map[string]string `river:"body"`
+	}) {
+		decoded = r.Body
+	})
+	rv := New().Handle("/", e)
+	rv.Use(Compress(Compression{MaxBodySize: 64}))
+
+	gz := func(s string) *bytes.Buffer {
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		w.Write([]byte(s))
+		w.Close()
+		return &b
+	}
+	tests := []struct {
+		method   string
+		body     *bytes.Buffer
+		encoding string
+		status   int
+	}{
+		{"POST", gz(`{"name":"river"}`), "gzip", 200},
+		{"POST", gz(`{"name":"` + strings.Repeat("a", 100) + `"}`), "gzip", 413},
+		// body parameters fail with the status of the body error.
+		{"PUT", gz(`{"name":"` + strings.Repeat("a", 100) + `"}`), "gzip", 413},
+		{"PUT", gz(`{"name":`), "gzip", 400},
+		{"POST", bytes.NewBufferString("not gzip"), "gzip", 400},
+		{"POST", bytes.NewBufferString(`{"name":"river"}`), "br", 415},
+		{"POST", bytes.NewBufferString(`{"name":"river"}`), "", 200},
+	}
+	for i, test := range tests {
+		decoded = nil
+		r := httptest.NewRequest(test.method, "/", test.body)
+		r.Header.Set("Content-Encoding", test.encoding)
+		w := httptest.NewRecorder()
+		rv.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("Test %d: expected status %d, found %d", i, test.status, w.Code)
+		}
+		if test.status == 200 && decoded["name"] != "river" {
+			t.Errorf("Test %d: expected decoded body, found %v", i, decoded)
+		}
+	}
+}
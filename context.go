@@ -1,7 +1,10 @@
 package river
 
 import (
+	"context"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -9,22 +12,51 @@ import (
 
 // Context is a request scope context.
 // Context implements http.ResponseWriter and embeds *http.Request.
+// Context also implements context.Context using the request context,
+// it is canceled when the client disconnects.
 //
 // It can be adapted for use in an http.Handler e.g.
 //  handler.ServeHTTP(c, c.Request)
+//
+// Contexts are reused across requests. A Context must not be used
+// after the handler returns, e.g. by a goroutine the handler started.
 type Context struct {
 	*http.Request
 	rw            http.ResponseWriter
 	params        httprouter.Params
+	route         string
+	requestID     string
 	values        map[string]interface{}
 	renderer      Renderer
 	errHandler    ErrHandler
+	onError       ErrHandler
 	middlewares   []Middleware
 	jsonDecoder   jsonDecoder
 	headerWritten bool
+	renderStatus  int
 	status        int
 	written       int
-	serviceInjector
+	cache         *CachePolicy
+	injector
+}
+
+// NewContext creates a Context for w and r outside of a River, e.g. to
+// call a handler in tests with Call. params are URL parameters as
+// key, value pairs. The Renderer is JSONRenderer.
+func NewContext(w http.ResponseWriter, r *http.Request, params ...string) *Context {
+	c := &Context{rw: w, Request: r, renderer: JSONRenderer}
+	for i := 0; i+1 < len(params); i += 2 {
+		c.params = append(c.params, httprouter.Param{Key: params[i], Value: params[i+1]})
+	}
+	c.initRequestID()
+	return c
+}
+
+// Call calls handler h for the request of c. Parameters of h are
+// resolved from services registered on c and its return values
+// are rendered.
+func (c *Context) Call(h Handler) {
+	handlerToMiddleware(h)(c)
 }
 
 // Param returns URL parameters. If key is not found,
@@ -36,6 +68,29 @@ func (c *Context) Param(key string) string {
 	return c.params.ByName(key)
 }
 
+// Route returns the path pattern of the handled route e.g. /user/:id.
+// Empty string is returned if the request is not handled by an endpoint.
+func (c *Context) Route() string {
+	return c.route
+}
+
+// ClientIP returns the IP address of the client. The first address in
+// X-Forwarded-For or X-Real-IP is used if set, otherwise RemoteAddr.
+// The headers can be set by clients and should only be trusted
+// behind a proxy that sets them.
+func (c *Context) ClientIP() string {
+	if fwd := c.Request.Header.Get("X-Forwarded-For"); fwd != "" {
+		return strings.TrimSpace(strings.Split(fwd, ",")[0])
+	}
+	if ip := c.Request.Header.Get("X-Real-IP"); ip != "" {
+		return strings.TrimSpace(ip)
+	}
+	if host, _, err := net.SplitHostPort(c.RemoteAddr); err == nil {
+		return host
+	}
+	return c.RemoteAddr
+}
+
 // Query returns URL query parameters. If key not found,
 // empty string is returned.
 func (c *Context) Query(key string) string {
@@ -77,12 +132,16 @@ func (c *Context) Header() http.Header {
 
 // Write writes the data to the connection as part of an HTTP reply.
 // If WriteHeader has not yet been called, Write calls WriteHeader(http.StatusOK)
-// before writing the data.  If the Header does not contain a
-// Content-Type line, Write adds a Content-Type set to the result of passing
-// the initial 512 bytes of written data to DetectContentType.
+// (or the status passed to Render) before writing the data.  If the Header
+// does not contain a Content-Type line, Write adds a Content-Type set to the
+// result of passing the initial 512 bytes of written data to DetectContentType.
 func (c *Context) Write(b []byte) (int, error) {
 	if !c.headerWritten {
-		c.WriteHeader(http.StatusOK)
+		status := http.StatusOK
+		if c.renderStatus != 0 {
+			status = c.renderStatus
+		}
+		c.WriteHeader(status)
 	}
 	n, err := c.rw.Write(b)
 	c.written += n
@@ -95,6 +154,7 @@ func (c *Context) Write(b []byte) (int, error) {
 // Thus explicit calls to WriteHeader are mainly used to
 // send error codes.
 func (c *Context) WriteHeader(status int) {
+	c.setCacheControl(status)
 	c.status = status
 	c.headerWritten = true
 	c.rw.WriteHeader(status)
@@ -106,10 +166,12 @@ func (c *Context) Get(key string) interface{} {
 	return c.values[key]
 }
 
-// Set sets key in context to value.
+// Set sets key in context to value. The value is also visible
+// to the request context i.e. c.Request.Context().Value(key).
 func (c *Context) Set(key string, value interface{}) {
 	if c.values == nil {
 		c.values = make(map[string]interface{})
+		c.setContext(valuesContext{Context: c.context(), values: c.values})
 	}
 	c.values[key] = value
 }
@@ -117,19 +179,38 @@ func (c *Context) Set(key string, value interface{}) {
 // Render renders data using the current endpoint's renderer (if any)
 // or global renderer (if any) or PlainRenderer; in that preference order.
 // status is HTTP status code to respond with.
+//
+// The response header is written when the renderer first writes,
+// a renderer can set headers or override status with WriteHeader.
+//
+// GET and HEAD requests with status 200 are responded with 304 if
+// If-None-Match or If-Modified-Since match the ETag or Last-Modified
+// response headers. ETags are computed if the caching policy has an
+// ETag mode.
 func (c *Context) Render(status int, data interface{}) {
-	c.WriteHeader(status)
-	err := c.renderer(c, data)
-	if err != nil && c.errHandler != nil {
-		c.errHandler(c, err)
-	}
+	c.render(status, c.renderer, data)
 }
 
 // RenderEmpty renders status text for status as body.
 // status is HTTP status code to respond with.
 func (c *Context) RenderEmpty(status int) {
-	c.WriteHeader(status)
-	err := PlainRenderer(c, http.StatusText(status))
+	c.render(status, PlainRenderer, http.StatusText(status))
+}
+
+func (c *Context) render(status int, renderer Renderer, data interface{}) {
+	c.renderStatus = status
+	var err error
+	switch {
+	case c.conditional(status) && c.notModified():
+		c.WriteHeader(http.StatusNotModified)
+	case c.cache != nil && c.cache.ETag != NoETag && !c.headerWritten:
+		err = c.renderCached(status, renderer, data)
+	default:
+		err = renderer(c, data)
+	}
+	if !c.headerWritten {
+		c.WriteHeader(status)
+	}
 	if err != nil && c.errHandler != nil {
 		c.errHandler(c, err)
 	}
@@ -172,20 +253,20 @@ func (c *Context) DecodeJSONBody(v interface{}) error {
 	return c.jsonDecoder.decode(v)
 }
 
-/* net/context / Go 1.7 Request.Context */
+/* context.Context backed by Request.Context */
 
 // Deadline returns the time when work done on behalf of this context
 // should be canceled. Deadline returns ok==false when no deadline is
 // set. Successive calls to Deadline return the same results.
 func (c *Context) Deadline() (deadline time.Time, ok bool) {
-	return
+	return c.context().Deadline()
 }
 
 // Done returns a channel that's closed when work done on behalf of this
-// context should be canceled. Done may return nil if this context can
-// never be canceled. Successive calls to Done return the same value.
+// context should be canceled. The channel is closed when the client
+// disconnects or a deadline set with WithTimeout or WithDeadline passes.
 func (c *Context) Done() <-chan struct{} {
-	return nil
+	return c.context().Done()
 }
 
 // Err returns a non-nil error value after Done is closed. Err returns
@@ -193,15 +274,77 @@ func (c *Context) Done() <-chan struct{} {
 // context's deadline passed. No other values for Err are defined.
 // After Done is closed, successive calls to Err return the same value.
 func (c *Context) Err() error {
-	return nil
+	return c.context().Err()
 }
 
 // Value returns the value associated with this context for key, or nil
-// if no value is associated with key. Successive calls to Value with
-// the same key returns the same result.
+// if no value is associated with key. Values set with Set and WithValue
+// are both visible.
 func (c *Context) Value(key interface{}) interface{} {
+	return c.context().Value(key)
+}
+
+// WithTimeout sets the request context to cancel after timeout.
+// The returned cancel function should be called to release
+// resources once the work is done.
+//  cancel := c.WithTimeout(5 * time.Second)
+//  defer cancel()
+//  c.Next()
+func (c *Context) WithTimeout(timeout time.Duration) context.CancelFunc {
+	ctx, cancel := context.WithTimeout(c.context(), timeout)
+	c.setContext(ctx)
+	return cancel
+}
+
+// WithDeadline sets the request context to cancel at deadline.
+// The returned cancel function should be called to release
+// resources once the work is done.
+func (c *Context) WithDeadline(deadline time.Time) context.CancelFunc {
+	ctx, cancel := context.WithDeadline(c.context(), deadline)
+	c.setContext(ctx)
+	return cancel
+}
+
+// WithCancel makes the request context cancelable with the
+// returned cancel function.
+func (c *Context) WithCancel() context.CancelFunc {
+	ctx, cancel := context.WithCancel(c.context())
+	c.setContext(ctx)
+	return cancel
+}
+
+// WithValue sets key to value in the request context. Unlike Set,
+// key can be of any comparable type.
+func (c *Context) WithValue(key, value interface{}) {
+	c.setContext(context.WithValue(c.context(), key, value))
+}
+
+func (c *Context) context() context.Context {
+	if c.Request == nil {
+		return context.Background()
+	}
+	return c.Request.Context()
+}
+
+func (c *Context) setContext(ctx context.Context) {
+	if c.Request == nil {
+		c.Request = &http.Request{}
+	}
+	c.Request = c.Request.WithContext(ctx)
+}
+
+// valuesContext exposes values set with Context.Set to
+// the request context.
+type valuesContext struct {
+	context.Context
+	values map[string]interface{}
+}
+
+func (v valuesContext) Value(key interface{}) interface{} {
 	if k, ok := key.(string); ok {
-		return c.Get(k)
+		if value, ok := v.values[k]; ok {
+			return value
+		}
 	}
-	return nil
+	return v.Context.Value(key)
 }
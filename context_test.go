@@ -0,0 +1,48 @@
+package river
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContext_Context(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Context{Request: httptest.NewRequest("GET", "/", nil).WithContext(ctx)}
+
+	c.Set("user", "river")
+	type key struct{}
+	c.WithValue(key{}, 1)
+
+	if v := c.Request.Context().Value("user"); v != "river" {
+		t.Errorf("expected river from request context, found %v", v)
+	}
+	if v := c.Value(key{}); v != 1 {
+		t.Errorf("expected 1, found %v", v)
+	}
+
+	cancel()
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context not canceled with request")
+	}
+	if c.Err() != context.Canceled {
+		t.Errorf("expected %v, found %v", context.Canceled, c.Err())
+	}
+}
+
+func TestContext_WithTimeout(t *testing.T) {
+	c := &Context{Request: httptest.NewRequest("GET", "/", nil)}
+	cancel := c.WithTimeout(time.Millisecond)
+	defer cancel()
+
+	if _, ok := c.Deadline(); !ok {
+		t.Error("expected deadline to be set")
+	}
+	<-c.Done()
+	if c.Request.Context().Err() != context.DeadlineExceeded {
+		t.Errorf("expected %v, found %v", context.DeadlineExceeded, c.Request.Context().Err())
+	}
+}
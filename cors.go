@@ -0,0 +1,226 @@
+package river
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS is a Cross-Origin Resource Sharing policy.
+// Preflight requests are answered automatically for endpoints
+// with a policy, unless the endpoint handles OPTIONS requests.
+type CORS struct {
+	// AllowOrigins are the allowed origins. "*" allows all origins and
+	// wildcards are supported in origins e.g. https://*.example.com.
+	AllowOrigins []string
+	// AllowOriginPatterns are regular expressions for allowed origins.
+	AllowOriginPatterns []*regexp.Regexp
+	// AllowMethods are the allowed methods.
+	// Defaults to the methods handled by the endpoint.
+	AllowMethods []string
+	// AllowHeaders are the allowed request headers.
+	// Defaults to the headers requested by the preflight request.
+	AllowHeaders []string
+	// ExposeHeaders are the response headers exposed to the client.
+	ExposeHeaders []string
+	// AllowCredentials allows cookies and authorization headers.
+	// It cannot be combined with the "*" origin.
+	AllowCredentials bool
+	// MaxAge is how long preflight results can be cached.
+	MaxAge time.Duration
+}
+
+// CORS sets the CORS policy for all endpoints.
+// An endpoint policy overrules this.
+func (rv *River) CORS(policy CORS) *River {
+	policy.mustBeValid()
+	rv.cors = &policy
+	rv.chainChanged()
+	return rv
+}
+
+// CORS sets the CORS policy for the endpoint and
+// endpoints mounted on it.
+func (e *Endpoint) CORS(policy CORS) *Endpoint {
+	policy.mustBeValid()
+	e.cors = &policy
+	e.chainChanged()
+	return e
+}
+
+// mustBeValid panics if policy allows credentials from any origin,
+// which would let any site make credentialed requests.
+func (policy CORS) mustBeValid() {
+	if !policy.AllowCredentials {
+		return
+	}
+	for _, o := range policy.AllowOrigins {
+		if o == "*" {
+			panic(`CORS AllowCredentials cannot be used with the "*" origin`)
+		}
+	}
+}
+
+// corsPolicy returns the CORS policy for e.
+func (rv *River) corsPolicy(e *Endpoint) *CORS {
+	for p := e; p != nil; p = p.parent {
+		if p.cors != nil {
+			return p.cors
+		}
+	}
+	return rv.cors
+}
+
+// serveCORS answers r if it is a preflight request for an endpoint
+// with a policy, unless the path is handled for OPTIONS.
+func (rv *River) serveCORS(w http.ResponseWriter, r *http.Request) bool {
+	method := r.Header.Get("Access-Control-Request-Method")
+	if r.Method != "OPTIONS" || r.Header.Get("Origin") == "" || method == "" {
+		return false
+	}
+	if h, _, _ := rv.r.Lookup("OPTIONS", r.URL.Path); h != nil {
+		return false
+	}
+	policy := rv.preflightPolicy(method, r.URL.Path)
+	if policy == nil {
+		return false
+	}
+	policy.preflight(w, r, rv.allowed(r.URL.Path))
+	return true
+}
+
+// preflightPolicy returns the CORS policy for a preflight request for path.
+// The policy of the endpoint handling method is preferred, otherwise
+// that of the endpoint handling any other method for path.
+func (rv *River) preflightPolicy(method, path string) *CORS {
+	var policy *CORS
+	for _, hp := range rv.handledPaths {
+		if h, _, _ := rv.r.Lookup(hp.method, path); h == nil || !matchRoute(hp.path, path) {
+			continue
+		}
+		p := rv.corsPolicy(hp.endpoint)
+		if hp.method == method && p != nil {
+			return p
+		}
+		if policy == nil {
+			policy = p
+		}
+	}
+	return policy
+}
+
+// matchRoute checks if path matches route, a path pattern
+// with :param and *catchAll segments.
+func matchRoute(route, path string) bool {
+	routeSegments, pathSegments := strings.Split(route, "/"), strings.Split(path, "/")
+	for i, s := range routeSegments {
+		if strings.HasPrefix(s, "*") {
+			return i < len(pathSegments)
+		}
+		if i >= len(pathSegments) {
+			return false
+		}
+		if strings.HasPrefix(s, ":") {
+			if pathSegments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if s != pathSegments[i] {
+			return false
+		}
+	}
+	return len(routeSegments) == len(pathSegments)
+}
+
+// corsMiddleware sets CORS headers on actual requests.
+func corsMiddleware(policy *CORS) Middleware {
+	return func(c *Context) {
+		if origin := c.Request.Header.Get("Origin"); origin != "" {
+			addVary(c.Header(), "Origin")
+			if policy.allowOrigin(c.Header(), origin) && len(policy.ExposeHeaders) > 0 {
+				c.Header().Set("Access-Control-Expose-Headers", strings.Join(policy.ExposeHeaders, ", "))
+			}
+		}
+		c.Next()
+	}
+}
+
+func (policy *CORS) preflight(w http.ResponseWriter, r *http.Request, allowed string) {
+	h := w.Header()
+	addVary(h, "Origin")
+	addVary(h, "Access-Control-Request-Method")
+	addVary(h, "Access-Control-Request-Headers")
+
+	if !policy.allowOrigin(h, r.Header.Get("Origin")) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	methods := allowed
+	if len(policy.AllowMethods) > 0 {
+		methods = strings.Join(policy.AllowMethods, ", ")
+	}
+	h.Set("Access-Control-Allow-Methods", methods)
+	h.Set("Allow", allowed)
+
+	if len(policy.AllowHeaders) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(policy.AllowHeaders, ", "))
+	} else if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+		h.Set("Access-Control-Allow-Headers", headers)
+	}
+	if policy.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge/time.Second)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowOrigin sets Access-Control-Allow-Origin and credentials
+// headers if origin is allowed.
+func (policy *CORS) allowOrigin(h http.Header, origin string) bool {
+	any, ok := false, false
+	for _, o := range policy.AllowOrigins {
+		if o == "*" {
+			any, ok = true, true
+			break
+		}
+		if matchOrigin(o, origin) {
+			ok = true
+			break
+		}
+	}
+	for _, re := range policy.AllowOriginPatterns {
+		if ok {
+			break
+		}
+		ok = re.MatchString(origin)
+	}
+	if !ok {
+		return false
+	}
+
+	if any {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if policy.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+// matchOrigin matches origin against pattern which may
+// contain a wildcard e.g. https://*.example.com.
+func matchOrigin(pattern, origin string) bool {
+	pattern, origin = strings.ToLower(pattern), strings.ToLower(origin)
+	i := strings.Index(pattern, "*")
+	if i < 0 {
+		return pattern == origin
+	}
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
@@ -0,0 +1,107 @@
+package river
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	handler := func(c *Context) { c.RenderEmpty(200) }
+	users := NewEndpoint().Get("/", handler).Post("/", handler).Put("/:id", handler).Get("/health", handler)
+	public := NewEndpoint().Get("/", handler)
+	custom := NewEndpoint().Get("/", handler).Options("/", func(c *Context) { c.RenderEmpty(299) })
+	rv := New().CORS(CORS{
+		AllowOrigins:        []string{"https://example.com", "https://*.example.org"},
+		AllowOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`^https://pr-\d+\.dev$`)},
+		ExposeHeaders:       []string{"X-Request-ID"},
+		AllowCredentials:    true,
+		MaxAge:              time.Minute,
+	})
+	rv.Handle("/users", users)
+	rv.Handle("/public", public.CORS(CORS{AllowOrigins: []string{"*"}}))
+	rv.Handle("/custom", custom)
+
+	tests := []struct {
+		method, path, origin, requestMethod string
+		status                              int
+		headers                             map[string]string
+	}{
+		{"OPTIONS", "/users", "https://example.com", "POST", 204, map[string]string{
+			"Access-Control-Allow-Origin":      "https://example.com",
+			"Access-Control-Allow-Methods":     "GET, OPTIONS, POST",
+			"Access-Control-Allow-Headers":     "Content-Type",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Max-Age":           "60",
+		}},
+		{"OPTIONS", "/users/1", "https://api.example.org", "PUT", 204, map[string]string{
+			"Access-Control-Allow-Origin":  "https://api.example.org",
+			"Access-Control-Allow-Methods": "OPTIONS, PUT",
+		}},
+		{"OPTIONS", "/users/health", "https://example.com", "GET", 204, map[string]string{
+			"Access-Control-Allow-Origin":  "https://example.com",
+			"Access-Control-Allow-Methods": "GET, OPTIONS, PUT",
+		}},
+		{"OPTIONS", "/users", "https://pr-12.dev", "GET", 204, map[string]string{
+			"Access-Control-Allow-Origin": "https://pr-12.dev",
+		}},
+		{"OPTIONS", "/users", "https://evil.com", "GET", 403, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+		{"OPTIONS", "/users", "https://example.org", "GET", 403, nil},
+		{"OPTIONS", "/public", "https://any.com", "GET", 204, map[string]string{
+			"Access-Control-Allow-Origin":      "*",
+			"Access-Control-Allow-Credentials": "",
+		}},
+		{"OPTIONS", "/custom", "https://example.com", "GET", 299, nil},
+		{"OPTIONS", "/missing", "https://example.com", "GET", 404, nil},
+		{"GET", "/users", "https://example.com", "", 200, map[string]string{
+			"Access-Control-Allow-Origin":   "https://example.com",
+			"Access-Control-Expose-Headers": "X-Request-ID",
+			"Vary":                          "Origin",
+		}},
+		{"GET", "/users", "https://evil.com", "", 200, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+		{"GET", "/public", "https://any.com", "", 200, map[string]string{
+			"Access-Control-Allow-Origin": "*",
+		}},
+	}
+	for i, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		r.Header.Set("Origin", test.origin)
+		if test.requestMethod != "" {
+			r.Header.Set("Access-Control-Request-Method", test.requestMethod)
+			r.Header.Set("Access-Control-Request-Headers", "Content-Type")
+		}
+		w := httptest.NewRecorder()
+		rv.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("Test %d: expected status %d, found %d", i, test.status, w.Code)
+		}
+		for k, v := range test.headers {
+			if found := w.Header().Get(k); found != v {
+				t.Errorf("Test %d: expected %s %q, found %q", i, k, v, found)
+			}
+		}
+	}
+}
+
+func TestCORS_credentialsAnyOrigin(t *testing.T) {
+	tests := []func(CORS){
+		func(policy CORS) { New().CORS(policy) },
+		func(policy CORS) { NewEndpoint().CORS(policy) },
+	}
+	for i, test := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Test %d: expected panic for credentials with any origin", i)
+				}
+			}()
+			test(CORS{AllowOrigins: []string{"https://example.com", "*"}, AllowCredentials: true})
+		}()
+	}
+}
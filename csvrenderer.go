@@ -0,0 +1,187 @@
+package river
+
+import (
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// CSVRenderer is csv renderer.
+//
+// Slices of structs are rendered with a header row and a row per item.
+// Nested struct fields are flattened and header names are taken from
+// csv tags, or field names if not tagged. Fields tagged with "-" are skipped.
+//  type User struct {
+//    ID      string  `csv:"id"`
+//    Address Address `csv:"address"` // address.city, address.country ...
+//  }
+// A struct is rendered as a single row and [][]string is rendered as is.
+func CSVRenderer(c *Context, data interface{}) error {
+	if isError(data) {
+		return ProblemRenderer(c, data)
+	}
+	records, err := csvRecords(data)
+	if err != nil {
+		return err
+	}
+	c.Header().Set("Content-Type", "text/csv")
+	w := csv.NewWriter(c)
+	if err := w.WriteAll(records); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+func csvRecords(data interface{}) ([][]string, error) {
+	if records, ok := data.([][]string); ok {
+		return records, nil
+	}
+
+	v := indirect(reflect.ValueOf(data))
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		itemType := v.Type().Elem()
+		for itemType.Kind() == reflect.Ptr {
+			itemType = itemType.Elem()
+		}
+		if !isCSVStruct(itemType) {
+			records := make([][]string, v.Len())
+			for i := range records {
+				records[i] = []string{csvValue(v.Index(i))}
+			}
+			return records, nil
+		}
+		fields := csvFields(itemType, nil, "")
+		records := [][]string{csvHeader(fields)}
+		for i := 0; i < v.Len(); i++ {
+			records = append(records, csvRow(v.Index(i), fields))
+		}
+		return records, nil
+	case reflect.Struct:
+		if !isCSVStruct(v.Type()) {
+			break
+		}
+		fields := csvFields(v.Type(), nil, "")
+		return [][]string{csvHeader(fields), csvRow(v, fields)}, nil
+	case reflect.Map, reflect.Func, reflect.Chan:
+		return nil, fmt.Errorf("cannot render %v as csv", v.Type())
+	}
+	return [][]string{{csvValue(v)}}, nil
+}
+
+// csvField is a flattened struct field.
+type csvField struct {
+	name  string
+	index []int
+}
+
+func csvFields(t reflect.Type, index []int, prefix string) []csvField {
+	var fields []csvField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("csv"); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+
+		fieldIndex := append(append([]int{}, index...), i)
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if isCSVStruct(ft) {
+			p := prefix + name + "."
+			if f.Anonymous && f.Tag.Get("csv") == "" {
+				p = prefix
+			}
+			fields = append(fields, csvFields(ft, fieldIndex, p)...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		fields = append(fields, csvField{name: prefix + name, index: fieldIndex})
+	}
+	return fields
+}
+
+func csvHeader(fields []csvField) []string {
+	header := make([]string, len(fields))
+	for i := range fields {
+		header[i] = fields[i].name
+	}
+	return header
+}
+
+func csvRow(v reflect.Value, fields []csvField) []string {
+	v = indirect(v)
+	row := make([]string, len(fields))
+	for i, f := range fields {
+		row[i] = csvValue(fieldByIndex(v, f.index))
+	}
+	return row
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but returns
+// an invalid value for nil embedded pointers instead of panicking.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		v = indirect(v)
+		if !v.IsValid() {
+			return v
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+func csvValue(v reflect.Value) string {
+	v = indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+	if v.Type().Implements(textMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = csvValue(v.Index(i))
+		}
+		return strings.Join(items, ";")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// isCSVStruct checks if t is a struct that should be flattened.
+func isCSVStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !t.Implements(textMarshalerType) &&
+		!reflect.PtrTo(t).Implements(textMarshalerType)
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
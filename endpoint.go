@@ -1,7 +1,9 @@
 package river
 
 import (
+	"fmt"
 	"net/http"
+	"path"
 	"reflect"
 )
 
@@ -12,20 +14,57 @@ import (
 // If a service is not previously registered and it is not one of
 // *river.Context, http.ResponseWriter and *http.Request, zero value of the type
 // (or nil if the type is a pointer) will be passed as the parameter.
+// Struct (or pointer to struct) parameters with river tags are filled
+// from the request instead, see Context.Bind.
 //
 // If there is an attempt to register a non function type as
 // a request handler, a panic occurs immediately. This
 // prevents possible runtime panic.
 //
-// The return values of the function (if any) are discarded.
+// The return values of the function (if any) are rendered with the
+// endpoint's Renderer. Supported return values are
+//  func(...) T
+//  func(...) (T, error)
+//  func(...) (int, T)
+//  func(...) (int, T, error)
+//  func(...) error
+// where the int is the response status. If the status is not returned,
+// it defaults to 201 for POST requests, 204 if there is no data
+// and 200 otherwise. A non nil error is passed to the handler
+// set with River.OnError instead.
+//
+// Nothing is rendered if the function has written a response.
 type Handler interface{}
 
 // Endpoint is a REST endpoint.
+//
+// Handlers can be added after the endpoint is handled or mounted,
+// but a handler for a method and path cannot be replaced; doing so panics.
 type Endpoint struct {
 	handlers map[string]endpointHandlers
 	renderer Renderer
 	middlewareChain
 	serviceInjector
+	parent   *Endpoint
+	children []childEndpoint
+	mounts   []endpointMount
+	docs     map[string]map[string]RouteDoc
+	cors     *CORS
+	cache    *CachePolicy
+	declared []reflect.Type
+	optional []reflect.Type
+}
+
+// childEndpoint is an endpoint mounted at prefix.
+type childEndpoint struct {
+	prefix   string
+	endpoint *Endpoint
+}
+
+// endpointMount is a path where an endpoint is handled by a River.
+type endpointMount struct {
+	rv   *River
+	path string
 }
 
 // NewEndpoint creates a new Endpoint.
@@ -71,6 +110,19 @@ func (e *Endpoint) Options(p string, h Handler) *Endpoint {
 	return e
 }
 
+// Use adds middlewares to the middleware chain.
+// Handled routes use the middlewares from their next request.
+func (e *Endpoint) Use(middlewares ...Middleware) {
+	e.middlewareChain.Use(middlewares...)
+	e.chainChanged()
+}
+
+// UseHandler adds any http.Handler as middleware to the middleware chain.
+func (e *Endpoint) UseHandler(middlewares ...http.Handler) {
+	e.middlewareChain.UseHandler(middlewares...)
+	e.chainChanged()
+}
+
 // Renderer sets the output renderer for endpoint.
 func (e *Endpoint) Renderer(r Renderer) *Endpoint {
 	e.renderer = r
@@ -83,12 +135,61 @@ func (e *Endpoint) Handle(requestMethod, p string, h Handler) *Endpoint {
 	return e
 }
 
+// Mount mounts child at prefix under e. child inherits e's
+// middlewares, Renderer and services; its own middlewares
+// run after e's and its own Renderer and services take precedence.
+//
+// An endpoint can only be mounted on one parent.
+func (e *Endpoint) Mount(prefix string, child *Endpoint) *Endpoint {
+	if child.parent != nil || len(child.mounts) > 0 {
+		panic("Endpoint is already mounted or handled")
+	}
+	for p := e; p != nil; p = p.parent {
+		if p == child {
+			panic("Cannot mount Endpoint on itself")
+		}
+	}
+	child.parent = e
+	e.children = append(e.children, childEndpoint{prefix: prefix, endpoint: child})
+	for _, m := range e.mounts {
+		m.rv.handle(path.Join(m.path, prefix), child)
+	}
+	return e
+}
+
 func (e *Endpoint) set(subpath string, method string, h Handler) {
 	if e.handlers[subpath] == nil {
 		e.handlers[subpath] = make(endpointHandlers)
 	}
 	mustBeHandler(h)
+	if _, ok := e.handlers[subpath][method]; ok && len(e.mounts) > 0 {
+		panic(fmt.Sprintf("%s %s is already handled, handlers cannot be replaced after Handle or Mount", method, subpath))
+	}
 	e.handlers[subpath][method] = h
+	for _, m := range e.mounts {
+		m.rv.handleRoute(method, m.path, subpath, h, e)
+	}
+}
+
+// middlewares returns the middlewares of e's ancestors followed by e's.
+func (e *Endpoint) middlewares() []Middleware {
+	if e.parent == nil {
+		return e.middlewareChain
+	}
+	parent := e.parent.middlewares()
+	middlewares := make([]Middleware, 0, len(parent)+len(e.middlewareChain))
+	return append(append(middlewares, parent...), e.middlewareChain...)
+}
+
+// inheritedRenderer returns the renderer of e or its closest ancestor
+// with a renderer.
+func (e *Endpoint) inheritedRenderer() Renderer {
+	for p := e; p != nil; p = p.parent {
+		if p.renderer != nil {
+			return p.renderer
+		}
+	}
+	return nil
 }
 
 // endpointHandlers maps request method to Handler.
@@ -103,22 +204,19 @@ func handlerToMiddleware(h Handler) Middleware {
 	}
 
 	mustBeHandler(h)
-	return func(c *Context) {
-		/* default injections */
-		// context
-		c.register(c)
-
-		// responseWriter
-		var rw http.ResponseWriter = c
-		c.register(rw)
 
-		// request
-		c.register(c.Request)
-
-		/* handle request */
-		c.invoke(h)
+	// fast paths without reflection.
+	switch handler := h.(type) {
+	case func(*Context):
+		return handler
+	case func(http.ResponseWriter, *http.Request):
+		return func(c *Context) {
+			handler(c, c.Request)
+		}
 	}
 
+	inv := compile(h)
+	return inv.call
 }
 
 func mustBeHandler(h Handler) {
@@ -127,4 +225,9 @@ func mustBeHandler(h Handler) {
 		// and prevent possible request time panic.
 		panic("Cannot use non function type as Handler")
 	}
+	if _, ok := resultOf(reflect.TypeOf(h)); !ok {
+		log.printf("Return values of %v are not rendered", reflect.TypeOf(h))
+	}
+	// panics for invalid river tags.
+	bindingsOf(reflect.TypeOf(h))
 }
@@ -0,0 +1,67 @@
+package river
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEndpoint_Mount(t *testing.T) {
+	type org string
+	var trace []string
+	mid := func(name string) Middleware {
+		return func(c *Context) {
+			trace = append(trace, name)
+			c.Next()
+		}
+	}
+
+	rv := New()
+	group := rv.Group("/org/:org")
+	group.Use(mid("org"))
+	group.Register(org("river"))
+	group.Renderer(PlainRenderer)
+
+	project := NewEndpoint().Get("/:id", func(c *Context, o org) string {
+		return string(o) + "/" + c.Param("org") + "/" + c.Param("id")
+	})
+	project.Use(mid("project"))
+	group.Mount("/project", project)
+
+	// handlers added after mounting are handled.
+	task := NewEndpoint()
+	project.Mount("/:id/task", task)
+	task.Get("/", func() string { return "tasks" })
+
+	tests := []struct {
+		path, body, trace string
+	}{
+		{"/org/a/project/1", "river/a/1", "org,project"},
+		{"/org/a/project/1/task", "tasks", "org,project"},
+	}
+	for i, test := range tests {
+		trace = nil
+		w := httptest.NewRecorder()
+		rv.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if body := w.Body.String(); body != test.body {
+			t.Errorf("Test %d: expected body %s, found %s", i, test.body, body)
+		}
+		if tr := strings.Join(trace, ","); tr != test.trace {
+			t.Errorf("Test %d: expected middlewares %s, found %s", i, test.trace, tr)
+		}
+	}
+}
+
+func TestEndpoint_replaceHandled(t *testing.T) {
+	e := NewEndpoint().Get("/", func() string { return "a" })
+	// replacing before Handle is allowed.
+	e.Get("/", func() string { return "b" })
+	New().Handle("/user", e)
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic replacing handled route")
+		}
+	}()
+	e.Get("/", func() string { return "c" })
+}
@@ -0,0 +1,140 @@
+package river
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error is an error response as described by RFC 7807.
+// Errors are rendered as application/problem+json by all renderers.
+//  return nil, &river.Error{Status: 404, Code: "user_not_found", Detail: "..."}
+type Error struct {
+	// Type is a URI reference that identifies the problem type.
+	// Defaults to about:blank.
+	Type string `json:"type,omitempty"`
+	// Title is a short summary of the problem type.
+	// Defaults to the status text of Status.
+	Title string `json:"title,omitempty"`
+	// Status is the HTTP status code.
+	Status int `json:"status,omitempty"`
+	// Detail is an explanation specific to this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Instance is a URI reference that identifies this occurrence of the problem.
+	Instance string `json:"instance,omitempty"`
+	// Code is an application specific error code.
+	Code string `json:"code,omitempty"`
+	// Fields are errors for specific request fields.
+	Fields []FieldError `json:"errors,omitempty"`
+	// RequestID is the request ID, for correlation with logs.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// FieldError is an error for a request field.
+type FieldError struct {
+	Field  string `json:"field"`
+	Code   string `json:"code,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// NewError creates a new Error with status and detail.
+func NewError(status int, detail string) *Error {
+	return &Error{Status: status, Detail: detail}
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Detail != "":
+		return e.Detail
+	case e.Title != "":
+		return e.Title
+	}
+	return http.StatusText(e.Status)
+}
+
+// ProblemRenderer renders data as application/problem+json.
+// data is converted to Error if it is not. The status of
+// the Error, if set, overrules the status passed to Render.
+//
+// The message of other errors with status 500 or above is not sent
+// to the client, it is logged with the request ID.
+func ProblemRenderer(c *Context, data interface{}) error {
+	e, internal := problemOf(data, c.renderStatus)
+	if internal != "" {
+		log.printf("Error in request %s: %s", c.RequestID(), internal)
+		if e.RequestID == "" {
+			e.RequestID = c.RequestID()
+		}
+	}
+	c.Header().Set("Content-Type", "application/problem+json")
+	if !c.headerWritten {
+		c.WriteHeader(e.Status)
+	}
+	return json.NewEncoder(c).Encode(e)
+}
+
+// problemOf converts data to an Error. status is used if
+// data does not have a status. An Error wrapped in data is
+// preferred over the mapping of BindError. internal is the message of data
+// if it is not an Error and the status is 500 or above, it is
+// omitted from the Error.
+func problemOf(data interface{}, status int) (problem *Error, internal string) {
+	var e Error
+	re, isError := errorOf(data)
+	err, _ := data.(error)
+	var be *BindError
+	switch {
+	case isError:
+		e = *re
+	case err != nil && errors.As(err, &be):
+		e = Error{
+			Status: http.StatusBadRequest,
+			Detail: be.Error(),
+			Fields: []FieldError{{Field: be.Name, Detail: be.Err.Error()}},
+		}
+	case err != nil:
+		e.Detail = err.Error()
+	default:
+		e.Detail = fmt.Sprint(data)
+	}
+
+	if e.Status == 0 {
+		e.Status = status
+	}
+	if e.Status < 400 {
+		e.Status = http.StatusInternalServerError
+	}
+	if e.Type == "" {
+		e.Type = "about:blank"
+	}
+	if e.Title == "" {
+		e.Title = http.StatusText(e.Status)
+	}
+	if !isError && e.Status >= 500 {
+		internal, e.Detail = e.Detail, ""
+	}
+	return &e, internal
+}
+
+// errorOf returns the Error in data, if any.
+func errorOf(data interface{}) (*Error, bool) {
+	switch err := data.(type) {
+	case *Error:
+		return err, true
+	case Error:
+		return &err, true
+	case error:
+		var re *Error
+		if errors.As(err, &re) {
+			return re, true
+		}
+	}
+	return nil, false
+}
+
+// statusOf returns the status of err or 500.
+func statusOf(err error) int {
+	problem, _ := problemOf(err, http.StatusInternalServerError)
+	return problem.Status
+}
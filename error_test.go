@@ -0,0 +1,65 @@
+package river
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	e := NewEndpoint().
+		Get("/:id", func(r struct {
+			ID int `river:"path=id"`
+		}) {
+		}).
+		Put("/:id", func() {}).
+		Post("/", func() { panic("failed") }).
+		Patch("/:id", func() error { return errors.New("pq: password auth failed for user admin") })
+	rv := New(Recovery()).Handle("/item", e)
+
+	tests := []struct {
+		method, path string
+		status       int
+		allow        string
+		fields       int
+	}{
+		{"GET", "/none", http.StatusNotFound, "", 0},
+		{"DELETE", "/item/1", http.StatusMethodNotAllowed, "GET, OPTIONS, PATCH, PUT", 0},
+		{"GET", "/item/one", http.StatusBadRequest, "", 1},
+		{"POST", "/item", http.StatusInternalServerError, "", 0},
+		{"PATCH", "/item/1", http.StatusInternalServerError, "", 0},
+	}
+	if allow := rv.allowed("/item/1"); allow != "GET, OPTIONS, PATCH, PUT" {
+		t.Errorf("expected allowed methods GET, OPTIONS, PATCH, PUT, found %s", allow)
+	}
+	for i, test := range tests {
+		w := httptest.NewRecorder()
+		rv.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != test.status {
+			t.Errorf("Test %d: expected status %d, found %d", i, test.status, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Errorf("Test %d: expected problem+json, found %s", i, ct)
+		}
+		if allow := w.Header().Get("Allow"); allow != test.allow {
+			t.Errorf("Test %d: expected Allow %q, found %q", i, test.allow, allow)
+		}
+
+		var problem Error
+		if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+		}
+		if problem.Status != test.status || problem.Title != http.StatusText(test.status) {
+			t.Errorf("Test %d: unexpected problem %+v", i, problem)
+		}
+		// messages of internal errors are not sent.
+		if test.status >= 500 && (problem.Detail != "" || problem.RequestID == "") {
+			t.Errorf("Test %d: expected problem with request ID and no detail, found %+v", i, problem)
+		}
+		if len(problem.Fields) != test.fields {
+			t.Errorf("Test %d: expected %d field errors, found %d", i, test.fields, len(problem.Fields))
+		}
+	}
+}
@@ -18,6 +18,7 @@ func main() {
 	infoEndpoint := river.NewEndpoint().
 		Get("/", sessionInfo)
 	infoEndpoint.Use(authMid)
+	infoEndpoint.Declare(Session{})
 	rv.Handle("/session", infoEndpoint)
 
 	authEndpoint := river.NewEndpoint().
@@ -33,8 +33,8 @@ func newAuthToken(c *river.Context) {
 }
 
 // sessionInfo handles GET /session.
-func sessionInfo(c *river.Context, session Session) {
-	c.Render(http.StatusOK, session)
+func sessionInfo(session Session) Session {
+	return session
 }
 
 var sessions = map[string]Session{}
@@ -23,8 +23,8 @@ func getUser(c *river.Context, model Model) {
 }
 
 // getAllUser handles GET /user.
-func getAllUser(c *river.Context, model Model) {
-	c.Render(http.StatusOK, model.getAll())
+func getAllUser(model Model) interface{} {
+	return model.getAll()
 }
 
 // addUser handles POST /user.
@@ -40,20 +40,20 @@ func addUser(c *river.Context, model Model) {
 	c.Render(http.StatusCreated, users)
 }
 
+// updateRequest is the request for PUT /user/:id.
+type updateRequest struct {
+	ID   string `river:"path=id"`
+	User User   `river:"body"`
+}
+
 // updateUser handles PUT /user/:id.
-func updateUser(c *river.Context, model Model) {
-	id := c.Param("id")
-	var user User
-	if err := c.DecodeJSONBody(&user); err != nil {
-		c.Render(http.StatusBadRequest, err)
-		return
-	}
-	model.put(id, user)
-	c.Render(http.StatusOK, user)
+func updateUser(req updateRequest, model Model) User {
+	model.put(req.ID, req.User)
+	return req.User
 }
 
 // deleteUser handles DELETE /user/:id.
-func deleteUser(c *river.Context, model Model) {
+func deleteUser(c *river.Context, model Model) error {
 	model.delete(c.Param("id"))
-	c.RenderEmpty(http.StatusNoContent)
+	return nil
 }
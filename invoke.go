@@ -0,0 +1,141 @@
+package river
+
+import (
+	"net/http"
+	"reflect"
+)
+
+var (
+	contextType        = reflect.TypeOf(&Context{})
+	responseWriterType = reflect.TypeOf((*http.ResponseWriter)(nil)).Elem()
+	requestType        = reflect.TypeOf(&http.Request{})
+	requestIDType      = reflect.TypeOf(RequestID(""))
+
+	// builtinTypes are always available to handlers. http.ResponseWriter
+	// is excluded as it is the Context.
+	builtinTypes = []reflect.Type{contextType, requestType, requestIDType}
+)
+
+func isBuiltin(t reflect.Type) bool {
+	for _, b := range builtinTypes {
+		if t == b {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the service of type t for the request of c.
+func (c *Context) resolve(t reflect.Type) (reflect.Value, error) {
+	switch t {
+	case contextType, responseWriterType:
+		return reflect.ValueOf(c), nil
+	case requestType:
+		return reflect.ValueOf(c.Request), nil
+	case requestIDType:
+		return reflect.ValueOf(RequestID(c.requestID)), nil
+	}
+	if _, ok := c.lookup(t); !ok && t.Kind() == reflect.Interface {
+		impl, err := c.implementation(t, builtinTypes...)
+		if err != nil || impl == nil {
+			return reflect.Zero(t), err
+		}
+		return c.resolve(impl)
+	}
+	return c.injector.resolve(t, c.resolve)
+}
+
+// invoke invokes function f with arguments resolved for the request of c.
+func (c *Context) invoke(f interface{}) ([]reflect.Value, error) {
+	return call(f, c.resolve)
+}
+
+// argKind is how a handler argument is resolved.
+type argKind int
+
+const (
+	argService argKind = iota
+	argContext
+	argRequest
+	argRequestID
+	argBinding
+)
+
+// handlerArg is a handler parameter.
+type handlerArg struct {
+	kind    argKind
+	typ     reflect.Type
+	binding *binding
+}
+
+// invocation is the injection plan of a handler, computed once
+// when the handler is registered.
+type invocation struct {
+	fn     reflect.Value
+	args   []handlerArg
+	result handlerResult
+}
+
+// compile computes the invocation of handler h. h must be a valid Handler.
+func compile(h Handler) *invocation {
+	t := reflect.TypeOf(h)
+	result, _ := resultOf(t)
+	inv := &invocation{fn: reflect.ValueOf(h), args: make([]handlerArg, t.NumIn()), result: result}
+
+	bindings := make(map[reflect.Type]*binding)
+	for _, b := range bindingsOf(t) {
+		bindings[b.typ] = b
+	}
+	for i := range inv.args {
+		arg := handlerArg{typ: t.In(i)}
+		switch arg.typ {
+		case contextType, responseWriterType:
+			arg.kind = argContext
+		case requestType:
+			arg.kind = argRequest
+		case requestIDType:
+			arg.kind = argRequestID
+		default:
+			if b, ok := bindings[arg.typ]; ok {
+				arg.kind, arg.binding = argBinding, b
+			}
+		}
+		inv.args[i] = arg
+	}
+	return inv
+}
+
+// call calls the handler for the request of c and renders its results.
+func (inv *invocation) call(c *Context) {
+	args := make([]reflect.Value, len(inv.args))
+	for i, arg := range inv.args {
+		switch arg.kind {
+		case argContext:
+			args[i] = reflect.ValueOf(c)
+		case argRequest:
+			args[i] = reflect.ValueOf(c.Request)
+		case argRequestID:
+			args[i] = reflect.ValueOf(RequestID(c.requestID))
+		case argBinding:
+			// a registered service takes precedence.
+			if _, ok := c.lookup(arg.typ); !ok {
+				v, err := arg.binding.value(c)
+				if err != nil {
+					c.Render(http.StatusBadRequest, err)
+					return
+				}
+				args[i] = v
+				break
+			}
+			fallthrough
+		default:
+			v, err := c.resolve(arg.typ)
+			if err != nil {
+				c.handleError(err)
+				return
+			}
+			args[i] = v
+		}
+	}
+	inv.result.render(c, inv.fn.Call(args))
+}
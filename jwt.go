@@ -0,0 +1,233 @@
+package river
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// JWTConfig configures the JWTAuth middleware. At least one of
+// Secret, PublicKey and JWKSFile must be set.
+type JWTConfig struct {
+	// Secret is the key for HS256 tokens.
+	Secret []byte
+	// PublicKey is the key for RS256 (*rsa.PublicKey)
+	// or ES256 (*ecdsa.PublicKey) tokens.
+	PublicKey crypto.PublicKey
+	// JWKSFile is a JSON Web Key Set file. Keys are
+	// selected by the kid header of tokens.
+	JWKSFile string
+	// Audience, if set, must be in the aud claim.
+	Audience string
+	// Issuer, if set, must be the iss claim.
+	Issuer string
+	// Leeway is the allowed clock skew for exp and nbf.
+	Leeway time.Duration
+	// AllowNoExpiry accepts tokens without an exp claim,
+	// which are otherwise rejected.
+	AllowNoExpiry bool
+}
+
+// JWTAuth creates a middleware for Bearer JWT authentication.
+// HS256, RS256 and ES256 signatures are supported, exp and nbf
+// claims are checked. Tokens without exp are rejected unless
+// AllowNoExpiry is set. The Principal subject is the sub claim.
+func JWTAuth(config JWTConfig) Middleware {
+	var keys map[string]crypto.PublicKey
+	if config.JWKSFile != "" {
+		var err error
+		if keys, err = loadJWKS(config.JWKSFile); err != nil {
+			panic(fmt.Sprintf("river: cannot load JWKS %s: %v", config.JWKSFile, err))
+		}
+	}
+	if config.Secret == nil && config.PublicKey == nil && keys == nil {
+		panic("river: JWTAuth requires Secret, PublicKey or JWKSFile")
+	}
+
+	return func(c *Context) {
+		token := c.Request.Header.Get("Authorization")
+		if len(token) < 7 || !strings.EqualFold(token[:7], "Bearer ") {
+			unauthorized(c, "Bearer", "Missing bearer token")
+			return
+		}
+		claims, err := config.verify(token[7:], keys, time.Now())
+		if err != nil {
+			unauthorized(c, fmt.Sprintf("Bearer error=\"invalid_token\", error_description=%q", err.Error()), err.Error())
+			return
+		}
+		sub, _ := claims["sub"].(string)
+		c.Register(Principal{Subject: sub, Scheme: "Bearer", Claims: claims})
+		c.Next()
+	}
+}
+
+// verify verifies the signature and claims of token.
+func (config JWTConfig) verify(token string, keys map[string]crypto.PublicKey, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.New("malformed token header")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed token signature")
+	}
+
+	var key interface{}
+	switch k, ok := keys[header.Kid]; {
+	case ok:
+		key = k
+	case header.Alg == "HS256" && config.Secret != nil:
+		key = config.Secret
+	default:
+		key = config.PublicKey
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.New("malformed token claims")
+	}
+	switch exp, ok := claims["exp"].(float64); {
+	case !ok && (claims["exp"] != nil || !config.AllowNoExpiry):
+		return nil, errors.New("token has no valid exp claim")
+	case ok && now.After(unixTime(exp).Add(config.Leeway)):
+		return nil, errors.New("token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Before(unixTime(nbf).Add(-config.Leeway)) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if config.Issuer != "" && claims["iss"] != config.Issuer {
+		return nil, errors.New("invalid issuer")
+	}
+	if config.Audience != "" && !hasAudience(claims["aud"], config.Audience) {
+		return nil, errors.New("invalid audience")
+	}
+	return claims, nil
+}
+
+// verifySignature verifies sig of signed with key. The key type must
+// match alg to prevent algorithm confusion.
+func verifySignature(alg string, key interface{}, signed string, sig []byte) error {
+	hash := sha256.Sum256([]byte(signed))
+	switch k := key.(type) {
+	case []byte:
+		if alg == "HS256" {
+			mac := hmac.New(sha256.New, k)
+			mac.Write([]byte(signed))
+			if !hmac.Equal(sig, mac.Sum(nil)) {
+				return errors.New("invalid signature")
+			}
+			return nil
+		}
+	case *rsa.PublicKey:
+		if alg == "RS256" {
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], sig) != nil {
+				return errors.New("invalid signature")
+			}
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		if alg == "ES256" {
+			if len(sig) != 64 {
+				return errors.New("invalid signature")
+			}
+			r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+			if !ecdsa.Verify(k, hash[:], r, s) {
+				return errors.New("invalid signature")
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported algorithm %q", alg)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(int64(seconds), 0)
+}
+
+// hasAudience checks if aud, a string or array of strings, contains audience.
+func hasAudience(aud interface{}, audience string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == audience
+	case []interface{}:
+		for i := range a {
+			if a[i] == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// loadJWKS loads the RSA, EC P-256 and symmetric keys of a JWKS file.
+func loadJWKS(file string) (map[string]crypto.PublicKey, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []struct {
+			Kty, Kid, Crv, N, E, X, Y, K string
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		fields := map[string][]byte{}
+		for name, v := range map[string]string{"n": k.N, "e": k.E, "x": k.X, "y": k.Y, "k": k.K} {
+			if fields[name], err = base64.RawURLEncoding.DecodeString(v); err != nil {
+				return nil, fmt.Errorf("key %s: invalid %s", k.Kid, name)
+			}
+		}
+		switch {
+		case k.Kty == "RSA":
+			keys[k.Kid] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(fields["n"]),
+				E: int(new(big.Int).SetBytes(fields["e"]).Int64()),
+			}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			keys[k.Kid] = &ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(fields["x"]),
+				Y:     new(big.Int).SetBytes(fields["y"]),
+			}
+		case k.Kty == "oct":
+			keys[k.Kid] = fields["k"]
+		default:
+			return nil, fmt.Errorf("key %s: unsupported key type %s %s", k.Kid, k.Kty, k.Crv)
+		}
+	}
+	return keys, nil
+}
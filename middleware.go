@@ -35,17 +35,24 @@ func toMiddleware(h http.Handler) Middleware {
 }
 
 // Recovery creates a panic recovery middleware.
-// handlers are called after recovery.
+// The panic is logged with the request ID.
+// handlers are called after recovery. If there are no handlers,
+// an Error with status 500 and the request ID is rendered,
+// without the panic value.
 func Recovery(handlers ...func(c *Context, err interface{})) Middleware {
 	return func(c *Context) {
 		defer func() {
 			if err := recover(); err != nil {
+				log.printf("Recovered from panic in request %s: %v", c.RequestID(), err)
 				if handlers != nil {
 					for i := range handlers {
 						handlers[i](c, err)
 					}
 				} else {
-					c.Render(http.StatusInternalServerError, err)
+					c.Render(http.StatusInternalServerError, &Error{
+						Status:    http.StatusInternalServerError,
+						RequestID: c.RequestID(),
+					})
 				}
 			}
 		}()
@@ -0,0 +1,137 @@
+package river
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MediaRenderer is a Renderer for a media type.
+type MediaRenderer struct {
+	MediaType string
+	Renderer  Renderer
+}
+
+// Negotiate creates a Renderer that selects one of renderers with
+// the Accept header of the request. q-values and wildcards are supported.
+//
+// The first renderer is used when the request has no Accept header.
+// If none of renderers is acceptable, 406 Not Acceptable is sent.
+//  rv.Renderer(river.Negotiate(
+//    river.MediaRenderer{"application/json", river.JSONRenderer},
+//    river.MediaRenderer{"text/plain", river.PlainRenderer},
+//  ))
+func Negotiate(renderers ...MediaRenderer) Renderer {
+	return func(c *Context, data interface{}) error {
+		addVary(c.Header(), "Accept")
+
+		r := negotiate(c.Request.Header.Get("Accept"), renderers)
+		if r == nil {
+			return ProblemRenderer(c, NewError(http.StatusNotAcceptable, "Acceptable media types are "+mediaTypes(renderers)))
+		}
+		return r(c, data)
+	}
+}
+
+// negotiate returns the renderer in renderers with the highest
+// quality for accept, or nil if none is acceptable.
+func negotiate(accept string, renderers []MediaRenderer) Renderer {
+	if len(renderers) == 0 {
+		return nil
+	}
+	if strings.TrimSpace(accept) == "" {
+		return renderers[0].Renderer
+	}
+
+	ranges := parseAccept(accept)
+	var best Renderer
+	var bestQ float64
+	for _, r := range renderers {
+		if q := quality(r.MediaType, ranges); q > bestQ {
+			best, bestQ = r.Renderer, q
+		}
+	}
+	return best
+}
+
+func mediaTypes(renderers []MediaRenderer) string {
+	types := make([]string, len(renderers))
+	for i := range renderers {
+		types[i] = renderers[i].MediaType
+	}
+	return strings.Join(types, ", ")
+}
+
+// mediaRange is a media range in an Accept header.
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+		r := mediaRange{q: 1}
+		r.typ, r.subtype = splitMediaType(mediaType)
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// quality returns the q-value of the most specific range in ranges
+// that matches mediaType, or 0 if none matches.
+func quality(mediaType string, ranges []mediaRange) float64 {
+	typ, subtype := splitMediaType(strings.ToLower(mediaType))
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+func splitMediaType(mediaType string) (typ, subtype string) {
+	if i := strings.Index(mediaType, ";"); i > -1 {
+		mediaType = mediaType[:i]
+	}
+	mediaType = strings.TrimSpace(mediaType)
+	if i := strings.Index(mediaType, "/"); i > -1 {
+		return mediaType[:i], mediaType[i+1:]
+	}
+	return mediaType, "*"
+}
+
+// addVary adds field to the Vary header if not present.
+func addVary(h http.Header, field string) {
+	for _, v := range h["Vary"] {
+		for _, f := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), field) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", field)
+}
@@ -0,0 +1,46 @@
+package river
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	e := NewEndpoint().Get("/", func() string { return "river" })
+	e.Renderer(Negotiate(
+		MediaRenderer{"application/json", JSONRenderer},
+		MediaRenderer{"text/plain", PlainRenderer},
+	))
+	rv := New().Handle("/", e)
+
+	tests := []struct {
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"", http.StatusOK, "application/json"},
+		{"text/plain", http.StatusOK, "text/plain"},
+		{"text/*", http.StatusOK, "text/plain"},
+		{"*/*", http.StatusOK, "application/json"},
+		{"application/json;q=0.5, text/plain", http.StatusOK, "text/plain"},
+		{"application/json;q=0.5, */*;q=0.1", http.StatusOK, "application/json"},
+		{"text/*;q=0.9, text/plain;q=0, */*;q=0.1", http.StatusOK, "application/json"},
+		{"application/xml", http.StatusNotAcceptable, "application/problem+json"},
+	}
+	for i, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+		rv.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("Test %d: expected status %d, found %d", i, test.status, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != test.contentType {
+			t.Errorf("Test %d: expected Content-Type %s, found %s", i, test.contentType, ct)
+		}
+		if v := w.Header().Get("Vary"); v != "Accept" {
+			t.Errorf("Test %d: expected Vary Accept, found %s", i, v)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package river
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPI is an OpenAPI 3 document.
+type OpenAPI struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components,omitempty"`
+}
+
+// OpenAPIInfo is the metadata of an OpenAPI document.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIOperation is an API operation on a path.
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []OpenAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter is an operation parameter.
+type OpenAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *OpenAPISchema `json:"schema,omitempty"`
+}
+
+// OpenAPIRequestBody is an operation request body.
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIResponse is an operation response.
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIMediaType is the schema for a media type.
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema,omitempty"`
+}
+
+// OpenAPIComponents holds reusable schemas.
+type OpenAPIComponents struct {
+	Schemas map[string]*OpenAPISchema `json:"schemas,omitempty"`
+}
+
+// OpenAPISchema is a JSON schema.
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+// RouteDoc documents a route in the OpenAPI document.
+// Request and Response are sample values, only their types are used.
+// They overrule types inferred from the handler.
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Request     interface{}
+	Response    interface{}
+	// Status is the success status. Defaults to the status for the method.
+	Status int
+}
+
+// Doc documents the route for method at subpath p.
+//  e.Get("/:id", getUser).
+//    Doc("GET", "/:id", river.RouteDoc{Summary: "Get a user", Response: User{}})
+func (e *Endpoint) Doc(method, p string, doc RouteDoc) *Endpoint {
+	if e.docs == nil {
+		e.docs = make(map[string]map[string]RouteDoc)
+	}
+	if e.docs[p] == nil {
+		e.docs[p] = make(map[string]RouteDoc)
+	}
+	e.docs[p][method] = doc
+	return e
+}
+
+// OpenAPIInfo sets the info of the OpenAPI document.
+func (rv *River) OpenAPIInfo(info OpenAPIInfo) *River {
+	rv.openAPIInfo = info
+	return rv
+}
+
+// OpenAPI generates an OpenAPI 3 document from the handled endpoints.
+//
+// Parameters and request bodies are inferred from handler parameters
+// with river tags, see Context.Bind. Response bodies are inferred
+// from handler return values. RouteDoc set with Endpoint.Doc
+// overrules inferred types.
+func (rv *River) OpenAPI() *OpenAPI {
+	doc := &OpenAPI{
+		OpenAPI: "3.0.3",
+		Info:    rv.openAPIInfo,
+		Paths:   make(map[string]map[string]*OpenAPIOperation),
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = "River"
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "1.0.0"
+	}
+
+	schemas := schemaGenerator{schemas: make(map[string]*OpenAPISchema), names: make(map[reflect.Type]string)}
+	problem := schemas.schemaOf(reflect.TypeOf(Error{}))
+
+	for _, hp := range rv.handledPaths {
+		if hp.endpoint == nil {
+			continue
+		}
+		var rd RouteDoc
+		if hp.endpoint.docs != nil {
+			rd = hp.endpoint.docs[hp.subpath][hp.method]
+		}
+		op := schemas.operation(hp.method, hp.path, hp.h, rd)
+		op.Responses["default"] = &OpenAPIResponse{
+			Description: "Error",
+			Content:     map[string]OpenAPIMediaType{"application/problem+json": {Schema: problem}},
+		}
+
+		p := openAPIPath(hp.path)
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = make(map[string]*OpenAPIOperation)
+		}
+		doc.Paths[p][strings.ToLower(hp.method)] = op
+	}
+	doc.Components.Schemas = schemas.schemas
+	return doc
+}
+
+// ServeOpenAPI handles GET requests at p with the OpenAPI document as JSON.
+func (rv *River) ServeOpenAPI(p string) *River {
+	e := NewEndpoint().Get("/", func(c *Context) {
+		c.Render(http.StatusOK, rv.OpenAPI())
+	})
+	e.Renderer(JSONRenderer)
+	return rv.Handle(p, e)
+}
+
+// openAPIPath converts httprouter path parameters to OpenAPI.
+// e.g. /user/:id -> /user/{id}
+func openAPIPath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// pathParams returns the parameter names in httprouter path p.
+func pathParams(p string) []string {
+	var params []string
+	for _, part := range strings.Split(p, "/") {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			params = append(params, part[1:])
+		}
+	}
+	return params
+}
+
+type schemaGenerator struct {
+	schemas map[string]*OpenAPISchema
+	names   map[reflect.Type]string
+}
+
+func (s schemaGenerator) operation(method, p string, h Handler, rd RouteDoc) *OpenAPIOperation {
+	op := &OpenAPIOperation{
+		Summary:     rd.Summary,
+		Description: rd.Description,
+		Tags:        rd.Tags,
+		Responses:   make(map[string]*OpenAPIResponse),
+	}
+
+	var requestType, responseType reflect.Type
+	params := make(map[string]bool)
+	t := reflect.TypeOf(h)
+	if t.Kind() == reflect.Func {
+		for _, b := range bindingsOf(t) {
+			st := b.typ
+			if st.Kind() == reflect.Ptr {
+				st = st.Elem()
+			}
+			for _, f := range b.fields {
+				ft := st.Field(f.index).Type
+				if f.source == "body" {
+					requestType = ft
+					continue
+				}
+				op.Parameters = append(op.Parameters, OpenAPIParameter{
+					Name:     f.name,
+					In:       f.source,
+					Required: f.source == "path",
+					Schema:   s.schemaOf(ft),
+				})
+				params[f.source+":"+f.name] = true
+			}
+		}
+		if result, ok := resultOf(t); ok && result.data > -1 {
+			responseType = t.Out(result.data)
+		}
+	}
+	for _, name := range pathParams(p) {
+		if !params["path:"+name] {
+			op.Parameters = append(op.Parameters, OpenAPIParameter{
+				Name: name, In: "path", Required: true, Schema: &OpenAPISchema{Type: "string"},
+			})
+		}
+	}
+	sort.SliceStable(op.Parameters, func(i, j int) bool {
+		return op.Parameters[i].In == "path" && op.Parameters[j].In != "path"
+	})
+
+	if rd.Request != nil {
+		requestType = reflect.TypeOf(rd.Request)
+	}
+	if rd.Response != nil {
+		responseType = reflect.TypeOf(rd.Response)
+	}
+
+	if requestType != nil {
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content:  map[string]OpenAPIMediaType{"application/json": {Schema: s.schemaOf(requestType)}},
+		}
+	}
+
+	status := rd.Status
+	if status == 0 {
+		status = defaultStatus(method, responseType != nil)
+	}
+	response := &OpenAPIResponse{Description: http.StatusText(status)}
+	if responseType != nil && status != http.StatusNoContent {
+		response.Content = map[string]OpenAPIMediaType{"application/json": {Schema: s.schemaOf(responseType)}}
+	}
+	op.Responses[strconv.Itoa(status)] = response
+	return op
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf returns the schema for t. Named struct types are added
+// to components and referenced.
+func (s schemaGenerator) schemaOf(t reflect.Type) *OpenAPISchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	}
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return &OpenAPISchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: s.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: s.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		name, ok := s.names[t]
+		if !ok {
+			name = s.schemaName(t)
+			s.names[t] = name
+			// register before generating for recursive types.
+			s.schemas[name] = &OpenAPISchema{}
+			*s.schemas[name] = *s.structSchema(t)
+		}
+		return &OpenAPISchema{Ref: "#/components/schemas/" + name}
+	}
+	// interface{} and others can be anything.
+	return &OpenAPISchema{}
+}
+
+// schemaName returns the component name for named type t. The name is
+// qualified by package path if another type has the same name.
+func (s schemaGenerator) schemaName(t reflect.Type) string {
+	name := componentName(t.Name())
+	if _, taken := s.schemas[name]; !taken {
+		return name
+	}
+	// types declared in functions share the package path.
+	name = componentName(t.PkgPath() + "." + t.Name())
+	for i, n := 2, name; ; i++ {
+		if _, taken := s.schemas[n]; !taken {
+			return n
+		}
+		n = name + "_" + strconv.Itoa(i)
+	}
+}
+
+// componentName replaces characters not allowed in component names.
+func componentName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		case r == '/':
+			return '.'
+		}
+		return '_'
+	}, name)
+}
+
+func (s schemaGenerator) structSchema(t reflect.Type) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag == "-" {
+			continue
+		} else if tag != "" {
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && ft.Kind() == reflect.Struct && f.Tag.Get("json") == "" {
+			for k, v := range s.structSchema(ft).Properties {
+				schema.Properties[k] = v
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		schema.Properties[name] = s.schemaOf(f.Type)
+	}
+	return schema
+}
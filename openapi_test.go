@@ -0,0 +1,95 @@
+package river
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRiver_OpenAPI(t *testing.T) {
+	type User struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Friends []User `json:"friends"`
+	}
+	type updateRequest struct {
+		ID    int    `river:"path=id"`
+		Token string `river:"header=X-Token"`
+		User  User   `river:"body"`
+	}
+
+	e := NewEndpoint().
+		Get("/", func() ([]User, error) { return nil, nil }).
+		Put("/:id", func(r updateRequest) User { return r.User }).
+		Delete("/:id", func(c *Context) {}).
+		Doc("DELETE", "/:id", RouteDoc{Summary: "Delete a user", Status: 204})
+	rv := New().Handle("/user", e).ServeOpenAPI("/openapi.json")
+
+	doc := rv.OpenAPI()
+	if len(doc.Paths) != 3 {
+		t.Fatalf("expected 3 paths, found %d", len(doc.Paths))
+	}
+
+	get := doc.Paths["/user"]["get"]
+	if s := get.Responses["200"].Content["application/json"].Schema; s.Type != "array" || s.Items.Ref != "#/components/schemas/User" {
+		t.Errorf("unexpected GET response schema %+v", s)
+	}
+
+	put := doc.Paths["/user/{id}"]["put"]
+	if len(put.Parameters) != 2 || put.Parameters[0].Name != "id" || put.Parameters[0].Schema.Type != "integer" {
+		t.Errorf("unexpected PUT parameters %+v", put.Parameters)
+	}
+	if put.RequestBody == nil || put.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/User" {
+		t.Errorf("unexpected PUT request body %+v", put.RequestBody)
+	}
+
+	del := doc.Paths["/user/{id}"]["delete"]
+	if del.Summary != "Delete a user" || del.Responses["204"] == nil {
+		t.Errorf("unexpected DELETE operation %+v", del)
+	}
+	if len(del.Parameters) != 1 || del.Parameters[0].In != "path" {
+		t.Errorf("unexpected DELETE parameters %+v", del.Parameters)
+	}
+
+	if friends := doc.Components.Schemas["User"].Properties["friends"]; friends == nil || friends.Items.Ref != "#/components/schemas/User" {
+		t.Errorf("unexpected recursive schema %+v", friends)
+	}
+
+	w := httptest.NewRecorder()
+	rv.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	var served OpenAPI
+	if err := json.NewDecoder(w.Body).Decode(&served); err != nil {
+		t.Fatal(err)
+	}
+	if served.OpenAPI != "3.0.3" || len(served.Paths) != 3 {
+		t.Errorf("unexpected served document %+v", served)
+	}
+}
+
+func TestRiver_OpenAPISchemaNames(t *testing.T) {
+	type URL struct {
+		Short string `json:"short"`
+	}
+
+	e := NewEndpoint().
+		Get("/short", func() URL { return URL{} }).
+		Get("/long", func() url.URL { return url.URL{} })
+	doc := New().Handle("/url", e).OpenAPI()
+
+	short := doc.Paths["/url/short"]["get"].Responses["200"].Content["application/json"].Schema.Ref
+	long := doc.Paths["/url/long"]["get"].Responses["200"].Content["application/json"].Schema.Ref
+	if short == long {
+		t.Fatalf("expected distinct schemas, found %s for both", short)
+	}
+	for _, ref := range []string{short, long} {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		if doc.Components.Schemas[name] == nil {
+			t.Errorf("expected schema %s", name)
+		}
+	}
+	if s := doc.Components.Schemas[strings.TrimPrefix(long, "#/components/schemas/")]; s.Properties["Scheme"] == nil {
+		t.Errorf("expected url.URL schema for %s, found %+v", long, s)
+	}
+}
@@ -0,0 +1,261 @@
+package river
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Scope is the lifetime of a provided service.
+type Scope int
+
+// Service scopes.
+const (
+	// Singleton services are created once and shared by all requests.
+	// They are disposed when the server shuts down. Their dependencies
+	// are resolved from registered services and other singletons.
+	Singleton Scope = iota
+	// RequestScope services are created once per request and
+	// disposed when the request ends.
+	RequestScope
+	// Transient services are created every time they are resolved and
+	// disposed when the request ends.
+	Transient
+)
+
+func (s Scope) String() string {
+	switch s {
+	case Singleton:
+		return "singleton"
+	case RequestScope:
+		return "request"
+	case Transient:
+		return "transient"
+	}
+	return fmt.Sprintf("Scope(%d)", int(s))
+}
+
+var cleanupType = reflect.TypeOf(func() {})
+
+// provider creates services with a factory function.
+type provider struct {
+	factory interface{}
+	typ     reflect.Type
+	scope   Scope
+
+	mu       sync.Mutex
+	created  bool
+	instance reflect.Value
+	cleanup  func()
+}
+
+// Provide registers factory as provider of services of its first
+// return type. factory is called when the service is resolved, its
+// parameters are resolved like handler parameters.
+//
+// factory returns the service and optionally a cleanup function
+// and an error. The cleanup function, or Close if the service is
+// an io.Closer, is called when the service is disposed.
+//  rv.Provide(func(c *river.Context, db *sql.DB) (*sql.Tx, func(), error) {
+//    tx, err := db.BeginTx(c, nil)
+//    return tx, func() {
+//      if c.Status() < 400 { tx.Commit() } else { tx.Rollback() }
+//    }, err
+//  }, river.RequestScope)
+func (s *serviceInjector) Provide(factory interface{}, scope Scope) {
+	t := reflect.TypeOf(factory)
+	if t == nil || t.Kind() != reflect.Func || !validProvider(t) {
+		panic(fmt.Sprintf("river: %v is not a valid provider, expected func(...) (T[, func()][, error])", t))
+	}
+	if scope < Singleton || scope > Transient {
+		panic(fmt.Sprintf("river: invalid scope %v", scope))
+	}
+	if *s == nil {
+		*s = make(serviceInjector)
+	}
+	(*s)[t.Out(0)] = &provider{factory: factory, typ: t.Out(0), scope: scope}
+}
+
+func validProvider(t reflect.Type) bool {
+	switch t.NumOut() {
+	case 1:
+		return true
+	case 2:
+		return t.Out(1) == errorType || t.Out(1) == cleanupType
+	case 3:
+		return t.Out(1) == cleanupType && t.Out(2) == errorType
+	}
+	return false
+}
+
+// resolve returns the service of type t in in. Request scoped
+// services are stored in the request services of in.
+// Dependencies of providers are resolved by r.
+func (in *injector) resolve(t reflect.Type, r resolver) (reflect.Value, error) {
+	service, layer, ok := in.find(t)
+	if !ok && t.Kind() == reflect.Interface {
+		impl, err := in.implementation(t)
+		if err != nil || impl == nil {
+			return reflect.Zero(t), err
+		}
+		return r(impl)
+	}
+	if !ok {
+		return reflect.Zero(t), nil
+	}
+	p, ok := service.(*provider)
+	if !ok {
+		return reflect.ValueOf(service), nil
+	}
+	if in.singleton != nil && p.scope != Singleton {
+		return reflect.Zero(t), fmt.Errorf("singleton %v cannot depend on %v scoped %v", in.singleton, p.scope, t)
+	}
+	if err := in.enter(t); err != nil {
+		return reflect.Zero(t), err
+	}
+	defer in.leave()
+
+	switch p.scope {
+	case Singleton:
+		layers := in.layers
+		if layer > 0 {
+			layers = layers[layer:]
+		}
+		return p.singleton(in.creating, layers)
+	case RequestScope:
+		v, cleanup, err := p.create(r)
+		if err != nil {
+			return reflect.Zero(t), err
+		}
+		if in.serviceInjector == nil {
+			in.serviceInjector = make(serviceInjector)
+		}
+		in.serviceInjector[t] = v.Interface()
+		in.disposer.add(cleanup)
+		return v, nil
+	}
+	v, cleanup, err := p.create(r)
+	if err != nil {
+		return reflect.Zero(t), err
+	}
+	in.disposer.add(cleanup)
+	return v, nil
+}
+
+// singleton returns the instance of p, creating it if not created.
+// Dependencies are resolved from layers, the services of the app, and
+// cannot be request services or providers of other scopes. creating
+// are the types of the providers being created.
+func (p *provider) singleton(creating []reflect.Type, layers []*serviceInjector) (reflect.Value, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.created {
+		return p.instance, nil
+	}
+
+	app := &injector{layers: layers, singleton: p.typ, creating: append([]reflect.Type(nil), creating...)}
+	var r resolver
+	r = func(t reflect.Type) (reflect.Value, error) {
+		if t == responseWriterType || isBuiltin(t) {
+			return reflect.Zero(t), fmt.Errorf("singleton %v cannot depend on request service %v", p.typ, t)
+		}
+		return app.resolve(t, r)
+	}
+	v, cleanup, err := p.create(r)
+	if err != nil {
+		return reflect.Zero(p.typ), err
+	}
+	p.instance, p.cleanup, p.created = v, cleanup, true
+	return p.instance, nil
+}
+
+// enter adds t to the providers being created,
+// or returns an error if t is being created.
+func (in *injector) enter(t reflect.Type) error {
+	for i := range in.creating {
+		if in.creating[i] == t {
+			return cycleError(append(in.creating[i:len(in.creating):len(in.creating)], t))
+		}
+	}
+	in.creating = append(in.creating, t)
+	return nil
+}
+
+func (in *injector) leave() {
+	in.creating = in.creating[:len(in.creating)-1]
+}
+
+// cycleError is the error of the dependency cycle of types.
+func cycleError(types []reflect.Type) error {
+	names := make([]string, len(types))
+	for i := range types {
+		names[i] = types[i].String()
+	}
+	return fmt.Errorf("dependency cycle %s", strings.Join(names, " -> "))
+}
+
+// create calls the factory of p with arguments resolved by r.
+func (p *provider) create(r resolver) (reflect.Value, func(), error) {
+	results, err := call(p.factory, r)
+	if err != nil {
+		return reflect.Value{}, nil, err
+	}
+	v := results[0]
+	var cleanup func()
+	for _, result := range results[1:] {
+		switch result.Type() {
+		case errorType:
+			if !result.IsNil() {
+				return reflect.Value{}, nil, result.Interface().(error)
+			}
+		case cleanupType:
+			cleanup, _ = result.Interface().(func())
+		}
+	}
+	if cleanup == nil {
+		if closer, ok := v.Interface().(io.Closer); ok && !isNil(v) {
+			cleanup = func() {
+				if err := closer.Close(); err != nil {
+					log.printf("Error closing %T: %v", closer, err)
+				}
+			}
+		}
+	}
+	return v, cleanup, nil
+}
+
+// dispose disposes the singleton instance of p, if created.
+func (p *provider) dispose() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.created && p.cleanup != nil {
+		p.cleanup()
+	}
+	p.created, p.instance, p.cleanup = false, reflect.Value{}, nil
+}
+
+// dispose disposes services created for the request of in.
+func (in *injector) dispose() {
+	in.disposer.dispose()
+}
+
+// disposer disposes services in reverse order of creation.
+type disposer struct {
+	cleanups []func()
+}
+
+func (d *disposer) add(cleanup func()) {
+	if cleanup != nil {
+		d.cleanups = append(d.cleanups, cleanup)
+	}
+}
+
+func (d *disposer) dispose() {
+	for i := len(d.cleanups) - 1; i >= 0; i-- {
+		d.cleanups[i]()
+		d.cleanups[i] = nil
+	}
+	d.cleanups = d.cleanups[:0]
+}
@@ -0,0 +1,160 @@
+package river
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type counter struct{ n int }
+
+type tx struct {
+	id        int
+	committed bool
+}
+
+type conn struct {
+	id     int
+	closed *[]int
+}
+
+func (c *conn) Close() error {
+	*c.closed = append(*c.closed, c.id)
+	return nil
+}
+
+func TestProvide(t *testing.T) {
+	var created, disposed []string
+	var closed []int
+	singletons, txs, conns := 0, 0, 0
+
+	e := NewEndpoint().Get("/", func(c *counter, t1 *tx, t2 *tx, c1 *conn, c2 *conn) (int, error) {
+		if t1 != t2 || c1 == c2 {
+			return 0, errors.New("unexpected instances")
+		}
+		return c.n, nil
+	})
+	e.Provide(func() *counter {
+		singletons++
+		created = append(created, "counter")
+		return &counter{n: singletons}
+	}, Singleton)
+	e.Provide(func(c *Context) (*tx, func(), error) {
+		txs++
+		created = append(created, "tx")
+		if c.Query("fail") != "" {
+			return nil, nil, NewError(503, "no database")
+		}
+		t := &tx{id: txs}
+		return t, func() {
+			t.committed = c.Status() < 400
+			disposed = append(disposed, "tx")
+		}, nil
+	}, RequestScope)
+	e.Provide(func(t *tx) *conn {
+		conns++
+		return &conn{id: conns*10 + t.id, closed: &closed}
+	}, Transient)
+	rv := New().Handle("/", e)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		rv.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != 200 || w.Body.String() != "1\n" {
+			t.Errorf("Test %d: expected 200 1, found %d %s", i, w.Code, w.Body)
+		}
+	}
+	if singletons != 1 || txs != 2 || conns != 4 {
+		t.Errorf("Expected 1 singleton, 2 txs and 4 conns, found %d, %d and %d", singletons, txs, conns)
+	}
+	if len(disposed) != 2 || len(closed) != 4 || closed[0] != 21 || closed[1] != 11 {
+		t.Errorf("Expected disposal in reverse order, found %v and %v", disposed, closed)
+	}
+
+	w := httptest.NewRecorder()
+	rv.ServeHTTP(w, httptest.NewRequest("GET", "/?fail=1", nil))
+	if w.Code != 503 {
+		t.Errorf("Expected provider error status 503, found %d", w.Code)
+	}
+}
+
+func TestProvideSingleton(t *testing.T) {
+	type a struct{}
+	type b struct{}
+	tests := []struct {
+		provide  func(e *Endpoint)
+		handler  Handler
+		expected string
+	}{
+		{func(e *Endpoint) {
+			e.Provide(func(c *Context) *counter { return &counter{} }, Singleton)
+		}, func(c *counter) {}, "singleton *river.counter cannot depend on request service *river.Context"},
+		{func(e *Endpoint) {
+			e.Provide(func(t *tx) *counter { return &counter{} }, Singleton)
+			e.Provide(func() *tx { return &tx{} }, RequestScope)
+		}, func(c *counter) {}, "singleton *river.counter cannot depend on request scoped *river.tx"},
+		{func(e *Endpoint) {
+			e.Provide(func(*b) *a { return &a{} }, Singleton)
+			e.Provide(func(*a) *b { return &b{} }, Singleton)
+		}, func(*a) {}, "dependency cycle *river.a -> *river.b -> *river.a"},
+		{func(e *Endpoint) {
+			e.Provide(func(*b) *a { return &a{} }, RequestScope)
+			e.Provide(func(*a) *b { return &b{} }, Transient)
+		}, func(*a) {}, "dependency cycle *river.a -> *river.b -> *river.a"},
+	}
+	for i, test := range tests {
+		e := NewEndpoint().Get("/", test.handler)
+		test.provide(e)
+		var err error
+		rv := New().Handle("/", e).OnError(func(c *Context, e error) { err = e })
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			rv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Test %d: deadlock resolving services", i)
+		}
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Test %d: expected error %q, found %v", i, test.expected, err)
+		}
+	}
+
+	// singleton dependencies are app services, not overrides of a request.
+	e := NewEndpoint().Get("/", func(c *counter) int { return c.n })
+	e.Register(tx{id: 1})
+	e.Provide(func(t tx) *counter { return &counter{n: t.id} }, Singleton)
+	rv := New().Handle("/", e)
+	overrides := &Overrides{}
+	overrides.Register(tx{id: 2})
+	w := httptest.NewRecorder()
+	rv.ServeHTTP(w, WithOverrides(httptest.NewRequest("GET", "/", nil), overrides))
+	if w.Body.String() != "1\n" {
+		t.Errorf("Expected singleton of app services 1, found %s", w.Body)
+	}
+}
+
+func TestProvideInvalid(t *testing.T) {
+	tests := []interface{}{
+		"not a func",
+		func() {},
+		func() (int, string) { return 0, "" },
+		func() (int, error, func()) { return 0, nil, nil },
+	}
+	for i, test := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Test %d: expected panic for %T", i, test)
+				}
+			}()
+			var s serviceInjector
+			s.Provide(test, Singleton)
+		}()
+	}
+}
@@ -0,0 +1,341 @@
+package river
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitPolicy configures the RateLimit middleware.
+type RateLimitPolicy struct {
+	// Limit is the number of requests allowed per Window.
+	Limit int
+	// Window is the time window of Limit.
+	Window time.Duration
+	// Key identifies the client of a request. Defaults to KeyByIP.
+	Key RateLimitKey
+	// Store keeps track of requests. Defaults to an in-memory TokenBucket.
+	Store RateLimitStore
+	// TrustedProxies are the IP addresses or CIDR ranges of proxies
+	// trusted to set X-Forwarded-For and X-Real-IP. The client IP is
+	// RemoteAddr for requests from other addresses.
+	TrustedProxies []string
+}
+
+// RateLimitKey returns the rate limit key of a request. Requests with
+// an empty key are limited by client IP, so clients without a key do
+// not share a limit.
+type RateLimitKey func(c *Context) string
+
+// RateLimitStore keeps rate limit state for keys. Keys are prefixed
+// with key: or ip:, see RateLimitKey.
+// An implementation determines the rate limiting algorithm.
+type RateLimitStore interface {
+	// Take counts a request for key, limited to limit requests
+	// per window, and returns the outcome.
+	Take(key string, limit int, window time.Duration) (RateLimitResult, error)
+}
+
+// RateLimitResult is the outcome of a rate limited request.
+type RateLimitResult struct {
+	// Allowed is true if the request is within the limit.
+	Allowed bool
+	// Remaining is the number of requests left.
+	Remaining int
+	// Reset is the time until the limit is fully restored.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimit creates a rate limiting middleware. Requests over the limit
+// are rendered an Error with status 429 using the current renderer.
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and Retry-After
+// headers are set.
+//  rv.Use(river.RateLimit(river.RateLimitPolicy{Limit: 100, Window: time.Minute}))
+func RateLimit(policy RateLimitPolicy) Middleware {
+	if policy.Limit <= 0 || policy.Window <= 0 {
+		panic("river: rate limit requires a positive Limit and Window")
+	}
+	proxies := parseProxies(policy.TrustedProxies)
+	if policy.Key == nil {
+		policy.Key = KeyByIP(policy.TrustedProxies...)
+	}
+	if policy.Store == nil {
+		policy.Store = TokenBucket()
+	}
+
+	return func(c *Context) {
+		result, err := policy.Store.Take(rateLimitKey(c, policy.Key, proxies), policy.Limit, policy.Window)
+		if err != nil {
+			log.printf("Rate limit error in request %s: %v", c.RequestID(), err)
+			c.Next()
+			return
+		}
+
+		h := c.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, seconds(policy.Window)))
+		if !result.Allowed {
+			h.Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+			c.Render(http.StatusTooManyRequests, &Error{
+				Status:    http.StatusTooManyRequests,
+				Detail:    "Rate limit exceeded",
+				RequestID: c.RequestID(),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitKey returns the store key of the request of c. Keys are
+// prefixed to keep client IPs and keys of key apart.
+func rateLimitKey(c *Context, key RateLimitKey, proxies []*net.IPNet) string {
+	if k := key(c); k != "" {
+		return "key:" + k
+	}
+	return "ip:" + clientIP(c.Request, proxies)
+}
+
+// seconds returns d in seconds, rounded up.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// KeyByIP limits requests by client IP. The client IP is RemoteAddr,
+// unless the request comes from one of trustedProxies, IP addresses or
+// CIDR ranges of proxies that set X-Forwarded-For or X-Real-IP.
+//  river.KeyByIP("10.0.0.0/8")
+func KeyByIP(trustedProxies ...string) RateLimitKey {
+	proxies := parseProxies(trustedProxies)
+	return func(c *Context) string {
+		return clientIP(c.Request, proxies)
+	}
+}
+
+// clientIP returns the IP address of the client of r. Forwarding
+// headers are only used for requests from proxies. X-Forwarded-For
+// is read from the right, as clients can set the addresses before
+// those added by proxies.
+func clientIP(r *http.Request, proxies []*net.IPNet) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if !isProxy(ip, proxies) {
+		return ip
+	}
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		addrs := strings.Split(fwd, ",")
+		for i := len(addrs) - 1; i >= 0; i-- {
+			ip = strings.TrimSpace(addrs[i])
+			if !isProxy(ip, proxies) {
+				break
+			}
+		}
+		return ip
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+	return ip
+}
+
+func isProxy(ip string, proxies []*net.IPNet) bool {
+	addr := net.ParseIP(ip)
+	for _, p := range proxies {
+		if addr != nil && p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseProxies parses IP addresses and CIDR ranges of trusted proxies.
+func parseProxies(proxies []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, p := range proxies {
+		cidr := p
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(fmt.Sprintf("river: invalid trusted proxy %q", p))
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// KeyByHeader limits requests by the value of header.
+func KeyByHeader(header string) RateLimitKey {
+	return func(c *Context) string {
+		return c.Request.Header.Get(header)
+	}
+}
+
+// KeyByRoute limits requests by route pattern, all clients
+// share the limit of a route.
+func KeyByRoute() RateLimitKey {
+	return func(c *Context) string {
+		return c.Method + " " + c.Route()
+	}
+}
+
+// KeyByValue limits requests by the value set for key with c.Set.
+func KeyByValue(key string) RateLimitKey {
+	return func(c *Context) string {
+		value := c.Get(key)
+		if value == nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}
+}
+
+// KeyByService limits requests by a registered service. f must be
+// a function of registered services that returns string.
+//  river.KeyByService(func(s Session) string { return s.Token })
+func KeyByService(f interface{}) RateLimitKey {
+	t := reflect.TypeOf(f)
+	if t == nil || t.Kind() != reflect.Func || t.NumOut() != 1 || t.Out(0).Kind() != reflect.String {
+		panic(fmt.Sprintf("river: %v is not a func returning string", t))
+	}
+	return func(c *Context) string {
+		results, err := c.invoke(f)
+		if err != nil {
+			log.printf("Rate limit key error in request %s: %v", c.RequestID(), err)
+			return ""
+		}
+		return results[0].String()
+	}
+}
+
+// TokenBucket creates an in-memory token bucket store. Buckets hold
+// up to limit tokens and are refilled at limit tokens per window,
+// allowing bursts up to limit.
+func TokenBucket() RateLimitStore {
+	return &memoryStore{now: time.Now, take: takeToken}
+}
+
+// SlidingWindow creates an in-memory sliding window store. Requests
+// are counted over a window that slides with time, weighting the
+// previous window by its overlap.
+func SlidingWindow() RateLimitStore {
+	return &memoryStore{now: time.Now, take: takeWindow}
+}
+
+// memoryStore is an in-memory RateLimitStore.
+type memoryStore struct {
+	sync.Mutex
+	entries map[string]*rateLimitEntry
+	swept   time.Time
+	now     func() time.Time
+	take    func(e *rateLimitEntry, now time.Time, limit int, window time.Duration) RateLimitResult
+}
+
+// rateLimitEntry is the state of a key. Token buckets use tokens and
+// last; sliding windows use start, count and prev.
+type rateLimitEntry struct {
+	tokens      float64
+	last        time.Time
+	start       time.Time
+	count, prev int
+	expires     time.Time
+}
+
+func (m *memoryStore) Take(key string, limit int, window time.Duration) (RateLimitResult, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	now := m.now()
+	if m.entries == nil {
+		m.entries = make(map[string]*rateLimitEntry)
+	}
+	if now.Sub(m.swept) > window {
+		m.sweep(now)
+	}
+	e, ok := m.entries[key]
+	if !ok {
+		e = &rateLimitEntry{tokens: float64(limit), last: now, start: now}
+		m.entries[key] = e
+	}
+	result := m.take(e, now, limit, window)
+	e.expires = now.Add(2 * window)
+	return result, nil
+}
+
+// sweep removes expired entries.
+func (m *memoryStore) sweep(now time.Time) {
+	for k, e := range m.entries {
+		if now.After(e.expires) {
+			delete(m.entries, k)
+		}
+	}
+	m.swept = now
+}
+
+func takeToken(e *rateLimitEntry, now time.Time, limit int, window time.Duration) RateLimitResult {
+	rate := float64(limit) / float64(window)
+	e.tokens = math.Min(float64(limit), e.tokens+float64(now.Sub(e.last))*rate)
+	e.last = now
+
+	var result RateLimitResult
+	if e.tokens >= 1 {
+		e.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - e.tokens) / rate)
+	}
+	result.Remaining = int(e.tokens)
+	result.Reset = time.Duration((float64(limit) - e.tokens) / rate)
+	return result
+}
+
+func takeWindow(e *rateLimitEntry, now time.Time, limit int, window time.Duration) RateLimitResult {
+	if elapsed := now.Sub(e.start); elapsed >= window {
+		e.prev = e.count
+		if elapsed >= 2*window {
+			e.prev = 0
+		}
+		e.count = 0
+		e.start = e.start.Add(elapsed / window * window)
+	}
+	elapsed := now.Sub(e.start)
+	weight := 1 - float64(elapsed)/float64(window)
+	estimate := float64(e.prev)*weight + float64(e.count)
+
+	var result RateLimitResult
+	if estimate+1 <= float64(limit) {
+		e.count++
+		estimate++
+		result.Allowed = true
+	} else if e.prev > 0 && e.count < limit {
+		// wait until the previous window's weight allows one more request.
+		w := 1 - float64(limit-e.count-1)/float64(e.prev)
+		result.RetryAfter = time.Duration(w*float64(window)) - elapsed
+	} else {
+		result.RetryAfter = window - elapsed
+	}
+	result.Remaining = int(math.Max(0, float64(limit)-estimate))
+	result.Reset = window - elapsed
+	if e.count > 0 {
+		// requests in this window count until the end of the next.
+		result.Reset += window
+	}
+	return result
+}
@@ -0,0 +1,138 @@
+package river
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitStores(t *testing.T) {
+	start := time.Unix(0, 0)
+	tests := []struct {
+		store   *memoryStore
+		offsets []time.Duration
+		allowed []bool
+	}{
+		// limit 2 per 10s
+		{TokenBucket().(*memoryStore),
+			[]time.Duration{0, 0, 0, 4 * time.Second, 5 * time.Second, 6 * time.Second},
+			[]bool{true, true, false, false, true, false}},
+		{SlidingWindow().(*memoryStore),
+			[]time.Duration{0, 1 * time.Second, 2 * time.Second, 11 * time.Second, 16 * time.Second, 16 * time.Second},
+			[]bool{true, true, false, false, true, false}},
+	}
+	for i, test := range tests {
+		for j, offset := range test.offsets {
+			test.store.now = func() time.Time { return start.Add(offset) }
+			result, _ := test.store.Take("key", 2, 10*time.Second)
+			if result.Allowed != test.allowed[j] {
+				t.Errorf("Test %d: expected request %d allowed %v, found %v", i, j, test.allowed[j], result.Allowed)
+			}
+			if !result.Allowed && result.RetryAfter <= 0 {
+				t.Errorf("Test %d: expected positive Retry-After for request %d, found %v", i, j, result.RetryAfter)
+			}
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	handler := func(c *Context) { c.RenderEmpty(200) }
+	e := NewEndpoint().Get("/", handler)
+	e.Use(func(c *Context) {
+		c.Register(c.Request.Header.Get("X-User"))
+		c.Next()
+	})
+	e.Use(RateLimit(RateLimitPolicy{
+		Limit:  2,
+		Window: time.Minute,
+		Key:    KeyByService(func(user string) string { return user }),
+	}))
+	rv := New().Handle("/", e)
+
+	tests := []struct {
+		user, ip  string
+		status    int
+		remaining string
+	}{
+		{"a", "10.0.0.1", 200, "1"},
+		{"a", "10.0.0.2", 200, "0"},
+		{"a", "10.0.0.1", 429, "0"},
+		{"b", "10.0.0.1", 200, "1"},
+		// requests without a key are limited by IP.
+		{"", "10.0.0.1", 200, "1"},
+		{"", "10.0.0.1", 200, "0"},
+		{"", "10.0.0.2", 200, "1"},
+		{"10.0.0.3", "10.0.0.4", 200, "1"},
+		{"", "10.0.0.3", 200, "1"},
+	}
+	for i, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-User", test.user)
+		r.RemoteAddr = test.ip + ":1234"
+		w := httptest.NewRecorder()
+		rv.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("Test %d: expected status %d, found %d", i, test.status, w.Code)
+		}
+		if found := w.Header().Get("RateLimit-Remaining"); found != test.remaining {
+			t.Errorf("Test %d: expected remaining %s, found %s", i, test.remaining, found)
+		}
+		if w.Header().Get("RateLimit-Limit") != "2" {
+			t.Errorf("Test %d: expected limit 2, found %s", i, w.Header().Get("RateLimit-Limit"))
+		}
+		if retry := w.Header().Get("Retry-After"); (test.status == 429) != (retry != "") {
+			t.Errorf("Test %d: unexpected Retry-After %q", i, retry)
+		}
+		if test.status == 429 && w.Header().Get("Content-Type") != "application/problem+json" {
+			t.Errorf("Test %d: expected problem response, found %s", i, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestKeyByIP(t *testing.T) {
+	tests := []struct {
+		remote, forwarded, realIP string
+		proxies                   []string
+		ip                        string
+	}{
+		{"10.0.0.1", "1.1.1.1", "2.2.2.2", nil, "10.0.0.1"},
+		{"10.0.0.1", "1.1.1.1", "", []string{"192.168.0.1"}, "10.0.0.1"},
+		{"10.0.0.1", "1.1.1.1", "", []string{"10.0.0.1"}, "1.1.1.1"},
+		{"10.0.0.1", "3.3.3.3, 1.1.1.1, 10.0.0.2", "", []string{"10.0.0.0/8"}, "1.1.1.1"},
+		{"10.0.0.1", "", "2.2.2.2", []string{"10.0.0.0/8"}, "2.2.2.2"},
+		{"10.0.0.1", "", "", []string{"10.0.0.0/8"}, "10.0.0.1"},
+		{"[::1]", "1.1.1.1", "", []string{"::1"}, "1.1.1.1"},
+	}
+	for i, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = test.remote + ":1234"
+		if test.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+		if test.realIP != "" {
+			r.Header.Set("X-Real-IP", test.realIP)
+		}
+		if ip := KeyByIP(test.proxies...)(&Context{Request: r}); ip != test.ip {
+			t.Errorf("Test %d: expected IP %s, found %s", i, test.ip, ip)
+		}
+	}
+}
+
+func TestRateLimit_forwardedFor(t *testing.T) {
+	e := NewEndpoint().Get("/", func(c *Context) { c.RenderEmpty(200) })
+	e.Use(RateLimit(RateLimitPolicy{Limit: 1, Window: time.Minute}))
+	rv := New().Handle("/", e)
+
+	for i, status := range []int{200, 429, 429} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("X-Forwarded-For", "1.1.1."+strconv.Itoa(i))
+		w := httptest.NewRecorder()
+		rv.ServeHTTP(w, r)
+		if w.Code != status {
+			t.Errorf("Test %d: expected status %d, found %d", i, status, w.Code)
+		}
+	}
+}
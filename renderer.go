@@ -2,29 +2,123 @@ package river
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"reflect"
+	"sort"
+
+	yaml "gopkg.in/yaml.v2"
 )
 
 // M is a convenience wrapper for map[string]interface{}.
 //  M{"status": "success, "data": M{"id": 1, "type": "complex"}}
 type M map[string]interface{}
 
+// MarshalXML encodes m as xml elements sorted by key.
+// The root element is named response unless set by the encoder.
+func (m M) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if start.Name.Local == "M" {
+		start.Name.Local = "response"
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := e.EncodeElement(m[k], xml.StartElement{Name: xml.Name{Local: k}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
 // Renderer renders data in a specified format.
 // Renderer should set Content-Type accordingly.
+//
+// Built-in renderers render errors with ProblemRenderer.
 type Renderer func(c *Context, data interface{}) error
 
 // JSONRenderer is json renderer.
 func JSONRenderer(c *Context, data interface{}) error {
+	if isError(data) {
+		return ProblemRenderer(c, data)
+	}
 	c.Header().Set("Content-Type", "application/json")
 	return json.NewEncoder(c).Encode(data)
 }
 
+// XMLRenderer is xml renderer. Slices and arrays are
+// rendered in a response root element.
+func XMLRenderer(c *Context, data interface{}) error {
+	if isError(data) {
+		return ProblemRenderer(c, data)
+	}
+	c.Header().Set("Content-Type", "application/xml")
+	if _, err := fmt.Fprint(c, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(c)
+	if v := reflect.ValueOf(data); (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) &&
+		v.Type().Elem().Kind() != reflect.Uint8 {
+		return encodeXMLList(enc, v)
+	}
+	return enc.Encode(data)
+}
+
+// encodeXMLList encodes the elements of slice or array v
+// in a response root element.
+func encodeXMLList(enc *xml.Encoder, v reflect.Value) error {
+	start := xml.StartElement{Name: xml.Name{Local: "response"}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := enc.Encode(v.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	if err := enc.EncodeToken(start.End()); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// YAMLRenderer is yaml renderer.
+func YAMLRenderer(c *Context, data interface{}) error {
+	if isError(data) {
+		return ProblemRenderer(c, data)
+	}
+	b, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+	c.Header().Set("Content-Type", "application/yaml")
+	_, err = c.Write(b)
+	return err
+}
+
 // PlainRenderer is plain text renderer.
 func PlainRenderer(c *Context, data interface{}) error {
+	if isError(data) {
+		return ProblemRenderer(c, data)
+	}
 	c.Header().Set("Content-Type", "text/plain")
 	_, err := fmt.Fprint(c, data)
 	return err
 }
 
+func isError(data interface{}) bool {
+	switch data.(type) {
+	case error, Error:
+		return true
+	}
+	return false
+}
+
 // ErrHandler handles error returned by Renderer.
 type ErrHandler func(c *Context, err error)
@@ -0,0 +1,64 @@
+package river
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func render(r Renderer, data interface{}) (*httptest.ResponseRecorder, error) {
+	w := httptest.NewRecorder()
+	c := &Context{rw: w, Request: httptest.NewRequest("GET", "/", nil)}
+	return w, r(c, data)
+}
+
+func TestRenderers(t *testing.T) {
+	type Address struct {
+		City string `csv:"city"`
+	}
+	type User struct {
+		ID      int       `csv:"id" yaml:"id"`
+		Name    string    `csv:"name" yaml:"name"`
+		Address Address   `csv:"address" yaml:"-"`
+		Created time.Time `csv:"created" yaml:"-"`
+		Secret  string    `csv:"-" yaml:"-"`
+	}
+	created := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	users := []User{
+		{1, "Ada", Address{"London"}, created, "x"},
+		{2, "Bob, Jr", Address{}, created, "y"},
+	}
+
+	tests := []struct {
+		renderer    Renderer
+		data        interface{}
+		contentType string
+		body        string
+	}{
+		{XMLRenderer, M{"id": 1, "name": "Ada"}, "application/xml",
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<response><id>1</id><name>Ada</name></response>`},
+		{XMLRenderer, []Address{{"London"}, {"Paris"}}, "application/xml",
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<response><Address><City>London</City></Address><Address><City>Paris</City></Address></response>`},
+		{YAMLRenderer, users[:1], "application/yaml",
+			"- id: 1\n  name: Ada\n"},
+		{CSVRenderer, users, "text/csv",
+			"id,name,address.city,created\n" +
+				"1,Ada,London,2016-01-02T03:04:05Z\n" +
+				"2,\"Bob, Jr\",,2016-01-02T03:04:05Z\n"},
+		{CSVRenderer, [][]string{{"a", "b"}}, "text/csv", "a,b\n"},
+	}
+	for i, test := range tests {
+		w, err := render(test.renderer, test.data)
+		if err != nil {
+			t.Errorf("Test %d: %v", i, err)
+			continue
+		}
+		if ct := w.Header().Get("Content-Type"); ct != test.contentType {
+			t.Errorf("Test %d: expected Content-Type %s, found %s", i, test.contentType, ct)
+		}
+		if body := w.Body.String(); body != test.body {
+			t.Errorf("Test %d: expected body %q, found %q", i, test.body, body)
+		}
+	}
+}
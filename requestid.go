@@ -0,0 +1,72 @@
+package river
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// RequestIDHeader is the header for request IDs.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID is the correlation ID of a request. It is available
+// for every request and can be a handler parameter.
+//  func(id river.RequestID) {...}
+type RequestID string
+
+// RequestID returns the correlation ID of the request.
+//
+// The ID is taken from the X-Request-ID header or the trace ID of the
+// traceparent header if present, otherwise it is generated. It is sent
+// back in the X-Request-ID response header.
+func (c *Context) RequestID() string {
+	return c.requestID
+}
+
+// initRequestID sets the request ID of c.
+func (c *Context) initRequestID() {
+	id := c.Request.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		id = traceID(c.Request.Header.Get("traceparent"))
+	}
+	if id == "" {
+		id = newRequestID()
+	}
+	c.requestID = id
+	c.Header().Set(RequestIDHeader, id)
+}
+
+// validRequestID checks that id is safe to log and echo.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// traceID returns the trace ID of a W3C traceparent header
+// or empty string if invalid.
+//  00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func traceID(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 {
+		return ""
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil || parts[1] == strings.Repeat("0", 32) {
+		return ""
+	}
+	return parts[1]
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
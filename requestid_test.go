@@ -0,0 +1,43 @@
+package river
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var injected RequestID
+	var fromContext string
+	e := NewEndpoint().Get("/", func(c *Context, id RequestID) {
+		injected, fromContext = id, c.RequestID()
+	})
+	rv := New().Handle("/", e)
+
+	tests := []struct {
+		header, value, expected string
+	}{
+		{RequestIDHeader, "abc-123", "abc-123"},
+		{"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{RequestIDHeader, "bad id\n", ""},
+		{"", "", ""},
+	}
+	for i, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.header != "" {
+			r.Header.Set(test.header, test.value)
+		}
+		w := httptest.NewRecorder()
+		rv.ServeHTTP(w, r)
+
+		id := w.Header().Get(RequestIDHeader)
+		if test.expected != "" && id != test.expected {
+			t.Errorf("Test %d: expected %s, found %s", i, test.expected, id)
+		}
+		if test.expected == "" && len(id) != 32 {
+			t.Errorf("Test %d: expected generated id, found %s", i, id)
+		}
+		if string(injected) != id || fromContext != id {
+			t.Errorf("Test %d: expected %s, found injected %s and context %s", i, id, injected, fromContext)
+		}
+	}
+}
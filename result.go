@@ -0,0 +1,113 @@
+package river
+
+import (
+	"net/http"
+	"reflect"
+)
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	intType   = reflect.TypeOf(0)
+)
+
+// handlerResult is the position of the return values of a Handler.
+// -1 indicates that the value is not returned.
+type handlerResult struct {
+	status, data, err int
+}
+
+// resultOf computes the handlerResult of function type t.
+// Supported return values are
+//  (T), (T, error), (int, T), (int, T, error) and (error).
+// Other return values are not rendered, false is returned for them.
+func resultOf(t reflect.Type) (handlerResult, bool) {
+	r := handlerResult{-1, -1, -1}
+	n := t.NumOut()
+	if n > 0 && t.Out(n-1) == errorType {
+		r.err = n - 1
+		n--
+	}
+	if n > 1 && t.Out(0) == intType {
+		r.status = 0
+	}
+	switch {
+	case n == 0:
+	case n == 1:
+		r.data = 0
+	case n == 2 && r.status == 0:
+		r.data = 1
+	default:
+		return handlerResult{-1, -1, -1}, false
+	}
+	return r, true
+}
+
+// render renders results of a handler call.
+// Nothing is rendered if the handler has written a response.
+func (r handlerResult) render(c *Context, results []reflect.Value) {
+	if r.err > -1 {
+		if err, _ := results[r.err].Interface().(error); err != nil {
+			c.handleError(err)
+			return
+		}
+	}
+	if c.headerWritten || (r.data < 0 && r.err < 0) {
+		return
+	}
+
+	var data interface{}
+	if r.data > -1 && !isNil(results[r.data]) {
+		data = results[r.data].Interface()
+	}
+
+	status := defaultStatus(c.Method, data != nil)
+	if r.status > -1 {
+		status = int(results[r.status].Int())
+	}
+
+	switch {
+	case data != nil:
+		c.Render(status, data)
+	case status == http.StatusNoContent || status == http.StatusNotModified:
+		c.WriteHeader(status)
+	default:
+		c.RenderEmpty(status)
+	}
+}
+
+// defaultStatus returns the response status for request method.
+func defaultStatus(method string, hasData bool) int {
+	switch {
+	case method == "POST":
+		return http.StatusCreated
+	case !hasData:
+		return http.StatusNoContent
+	}
+	return http.StatusOK
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// handleError handles err returned by an endpoint handler.
+func (c *Context) handleError(err error) {
+	if c.onError != nil {
+		c.onError(c, err)
+		return
+	}
+	defaultErrorHandler(c, err)
+}
+
+func defaultErrorHandler(c *Context, err error) {
+	if c.headerWritten {
+		// the response has been written, it cannot be replaced.
+		log.printf("Error in request %s after response was written: %v", c.RequestID(), err)
+		return
+	}
+	c.Render(statusOf(err), err)
+}
@@ -0,0 +1,69 @@
+package river
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlerResult(t *testing.T) {
+	type item struct{ Name string }
+	e := NewEndpoint().
+		Get("/data", func() item { return item{"river"} }).
+		Get("/status", func() (int, item) { return http.StatusAccepted, item{"river"} }).
+		Get("/nil", func() (*item, error) { return nil, nil }).
+		Get("/error", func() (item, error) { return item{}, errors.New("failed") }).
+		Get("/missing", func() (*item, error) { return nil, NewError(http.StatusNotFound, "") }).
+		Post("/", func() error { return nil }).
+		Put("/", func(c *Context) error {
+			c.RenderEmpty(http.StatusAccepted)
+			return errors.New("failed after writing")
+		}).
+		Delete("/", func(c *Context) error {
+			c.RenderEmpty(http.StatusGone)
+			return nil
+		})
+	rv := New().Handle("/", e)
+
+	tests := []struct {
+		method, path string
+		status       int
+		body         string
+	}{
+		{"GET", "/data", http.StatusOK, `{"Name":"river"}`},
+		{"GET", "/status", http.StatusAccepted, `{"Name":"river"}`},
+		{"GET", "/nil", http.StatusNoContent, ``},
+		{"GET", "/error", http.StatusInternalServerError, `{"type":"about:blank","title":"Internal Server Error","status":500,"request_id":"req-1"}`},
+		{"GET", "/missing", http.StatusNotFound, `{"type":"about:blank","title":"Not Found","status":404}`},
+		{"POST", "/", http.StatusCreated, `Created`},
+		{"PUT", "/", http.StatusAccepted, `Accepted`},
+		{"DELETE", "/", http.StatusGone, `Gone`},
+	}
+	for i, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(test.method, test.path, nil)
+		r.Header.Set(RequestIDHeader, "req-1")
+		rv.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("Test %d: expected status %d, found %d", i, test.status, w.Code)
+		}
+		if body := strings.TrimSpace(w.Body.String()); body != test.body {
+			t.Errorf("Test %d: expected body %s, found %s", i, test.body, body)
+		}
+	}
+}
+
+func TestResultOf_unsupported(t *testing.T) {
+	var called bool
+	rv := New().Handle("/", NewEndpoint().Get("/", func() (string, string) {
+		called = true
+		return "a", "b"
+	}))
+	w := httptest.NewRecorder()
+	rv.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if !called || w.Code != 200 || w.Body.Len() != 0 {
+		t.Errorf("Expected handler to be called and results ignored, found %v %d %q", called, w.Code, w.Body.String())
+	}
+}
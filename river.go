@@ -3,6 +3,9 @@ package river
 import (
 	"net/http"
 	"path"
+	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -14,7 +17,18 @@ type River struct {
 	renderer Renderer
 	serviceInjector
 	errHandler ErrHandler
+	onError    ErrHandler
 	verbose
+	endpoints   []*Endpoint
+	life        lifecycle
+	openAPIInfo OpenAPIInfo
+	accessLog   *AccessLog
+	cors        *CORS
+	cache       *CachePolicy
+	declared    []reflect.Type
+	optional    []reflect.Type
+	// version is incremented when middleware chains change.
+	version uint64
 }
 
 // New creates a new River and initiates with middlewares.
@@ -34,58 +48,73 @@ func New(middlewares ...Middleware) *River {
 }
 
 func (rv *River) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if rv.serveCORS(w, r) {
+		return
+	}
 	rv.r.ServeHTTP(w, r)
 }
 
 // Handle handles endpoint at path p.
-// This should only be called after Endpoint requests have been handled.
+// Endpoints mounted on e are handled under p. Requests and endpoints
+// added to e afterwards are also handled.
 func (rv *River) Handle(p string, e *Endpoint) *River {
 	rv.handle(p, e)
 	return rv
 }
 
-func (rv *River) routerHandle(h Handler, e *Endpoint) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		c := &Context{
-			rw:              w,
-			Request:         r,
-			params:          p,
-			renderer:        notNilRenderer(e.renderer, rv.renderer),
-			middlewares:     composeMiddlewares(rv, handlerToMiddleware(h), e),
-			serviceInjector: copyInjectors(rv.serviceInjector, e.serviceInjector),
-		}
-		c.Next()
-	}
+// Use adds middlewares to the middleware chain.
+// Handled routes use the middlewares from their next request.
+func (rv *River) Use(middlewares ...Middleware) {
+	rv.middlewareChain.Use(middlewares...)
+	rv.chainChanged()
+}
+
+// UseHandler adds any http.Handler as middleware to the middleware chain.
+func (rv *River) UseHandler(middlewares ...http.Handler) {
+	rv.middlewareChain.UseHandler(middlewares...)
+	rv.chainChanged()
+}
+
+// Group creates an Endpoint handled at prefix. Endpoints mounted on the
+// group inherit its middlewares, Renderer and services.
+//  org := rv.Group("/org/:org")
+//  org.Use(authMid)
+//  org.Mount("/project", projectEndpoint)
+func (rv *River) Group(prefix string) *Endpoint {
+	e := NewEndpoint()
+	rv.handle(prefix, e)
+	return e
+}
+
+func (rv *River) routerHandle(p string, h Handler, e *Endpoint) httprouter.Handle {
+	rt := &route{rv: rv, path: p, e: e, handler: handlerToMiddleware(h)}
+	return rt.serve
 }
 
 func (rv *River) routerHandleNoEndpoint(handler Middleware) http.HandlerFunc {
+	rt := &route{rv: rv, handler: handler}
 	return func(w http.ResponseWriter, r *http.Request) {
-		c := &Context{
-			rw:              w,
-			Request:         r,
-			renderer:        notNilRenderer(rv.renderer),
-			middlewares:     composeMiddlewares(rv, handler, nil),
-			serviceInjector: copyInjectors(rv.serviceInjector),
-		}
-		c.Next()
+		rt.serve(w, r, nil)
 	}
 }
 
 func (rv *River) handle(p string, e *Endpoint) {
+	rv.endpoints = append(rv.endpoints, e)
+	e.mounts = append(e.mounts, endpointMount{rv: rv, path: p})
 	for subPath := range e.handlers {
-		fullPath := path.Join(p, subPath)
 		for method, handler := range e.handlers[subPath] {
-			rv.r.Handle(method, fullPath, rv.routerHandle(handler, e))
-			rv.handledPaths.add(method, fullPath, nameOf(handler))
+			rv.handleRoute(method, p, subPath, handler, e)
 		}
 	}
+	for _, child := range e.children {
+		rv.handle(path.Join(p, child.prefix), child.endpoint)
+	}
 }
 
-// Run starts River as an http server.
-func (rv *River) Run(addr string) error {
-	log.printf("Server started on %s", addr)
-	rv.Dump()
-	return http.ListenAndServe(addr, rv)
+func (rv *River) handleRoute(method, p, subPath string, h Handler, e *Endpoint) {
+	fullPath := path.Join(p, subPath)
+	rv.r.Handle(method, fullPath, rv.routerHandle(fullPath, h, e))
+	rv.handledPaths.add(method, fullPath, subPath, h, e)
 }
 
 // Renderer sets output renderer.
@@ -96,16 +125,43 @@ func (rv *River) Renderer(r Renderer) *River {
 }
 
 // NotAllowed replaces the default handler for methods not handled by
-// any endpoint with h.
+// any endpoint with h. The Allow header is set before h is called.
 func (rv *River) NotAllowed(h Handler) *River {
-	if handler, ok := h.(Middleware); ok {
-		rv.r.MethodNotAllowed = rv.routerHandleNoEndpoint(handler)
+	var handler http.HandlerFunc
+	if m, ok := h.(Middleware); ok {
+		handler = rv.routerHandleNoEndpoint(m)
 	} else {
-		rv.r.MethodNotAllowed = rv.routerHandleNoEndpoint(handlerToMiddleware(h))
+		handler = rv.routerHandleNoEndpoint(handlerToMiddleware(h))
 	}
+	rv.r.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if w.Header().Get("Allow") == "" {
+			w.Header().Set("Allow", rv.allowed(r.URL.Path))
+		}
+		handler(w, r)
+	})
 	return rv
 }
 
+// allowed returns the comma separated methods handled for path.
+func (rv *River) allowed(path string) string {
+	var methods []string
+	seen := make(map[string]bool)
+	for _, hp := range rv.handledPaths {
+		if seen[hp.method] {
+			continue
+		}
+		seen[hp.method] = true
+		if h, _, _ := rv.r.Lookup(hp.method, path); h != nil {
+			methods = append(methods, hp.method)
+		}
+	}
+	if h, _, _ := rv.r.Lookup("OPTIONS", path); len(methods) > 0 && rv.r.HandleOPTIONS && h == nil {
+		methods = append(methods, "OPTIONS")
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
 // NotFound replaces the default handler for request paths without
 // any endpoint.
 func (rv *River) NotFound(h Handler) *River {
@@ -124,25 +180,41 @@ func (rv *River) RenderError(h ErrHandler) *River {
 	return rv
 }
 
+// OnError sets the handler that handles non nil error
+// returned by endpoint handlers.
+// The default handler renders the error with the status of
+// the error if it is an Error, or 500.
+func (rv *River) OnError(h ErrHandler) *River {
+	rv.onError = h
+	return rv
+}
+
+// composeMiddlewares returns a new middleware chain for handler h of e.
+// The chain does not share memory with middlewares of rv or e.
 func composeMiddlewares(rv *River, h Middleware, e *Endpoint) []Middleware {
 	var middlewares []Middleware
+	if logger := rv.accessLogger(); logger != nil {
+		middlewares = append(middlewares, logger)
+	}
 	if e != nil {
-		middlewares = append(rv.middlewareChain, append(e.middlewareChain, h)...)
-	} else {
-		middlewares = append(rv.middlewareChain, h)
+		if policy := rv.corsPolicy(e); policy != nil {
+			middlewares = append(middlewares, corsMiddleware(policy))
+		}
 	}
-	if LogRequests {
-		middlewares = append([]Middleware{requestLogger()}, middlewares...)
+	middlewares = append(middlewares, rv.middlewareChain...)
+	if e != nil {
+		middlewares = append(middlewares, e.middlewares()...)
 	}
-	return middlewares
+	return append(middlewares, h)
 }
 
 func notFound(c *Context) {
-	c.Render(http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	c.Render(http.StatusNotFound, NewError(http.StatusNotFound, "No endpoint for "+c.URL.Path))
 }
 
 func notAllowed(c *Context) {
-	c.Render(http.StatusMethodNotAllowed, http.StatusText(http.StatusNotFound))
+	c.Render(http.StatusMethodNotAllowed, NewError(http.StatusMethodNotAllowed,
+		c.Method+" is not allowed, allowed methods are "+c.Header().Get("Allow")))
 }
 
 func notNilRenderer(r ...Renderer) Renderer {
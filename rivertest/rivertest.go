@@ -0,0 +1,298 @@
+// Package rivertest provides utilities for testing River endpoints and handlers.
+//
+//  client := rivertest.New(t, rv).Mock(mockUserModel)
+//  client.Get("/user/1").
+//    WithHeader("Authorization", "Bearer token").
+//    ExpectStatus(200).
+//    ExpectJSON(river.M{"id": "1", "name": "River"})
+package rivertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/abiosoft/river"
+)
+
+// Client is a test client for a River.
+type Client struct {
+	t      testing.TB
+	rv     *river.River
+	header http.Header
+	mocks  []mock
+}
+
+// mock is a service that overrules a registered service,
+// iface is nil unless registered for an interface.
+type mock struct {
+	iface, service interface{}
+}
+
+// New creates a test client for rv.
+func New(t testing.TB, rv *river.River) *Client {
+	return &Client{t: t, rv: rv, header: http.Header{}}
+}
+
+// NewEndpoint creates a test client for e handled at /.
+func NewEndpoint(t testing.TB, e *river.Endpoint) *Client {
+	return New(t, river.New().Handle("/", e))
+}
+
+// NewHandler creates a test client for a single handler h, handled
+// for method requests at route with a River. route can have params
+// e.g. /user/:id. See Call to call h without a River.
+//  rivertest.NewHandler(t, "GET", "/user/:id", getUser).Get("/user/1").ExpectStatus(200)
+func NewHandler(t testing.TB, method, route string, h river.Handler) *Client {
+	return NewEndpoint(t, river.NewEndpoint().Handle(method, route, h))
+}
+
+// River returns the River under test.
+func (c *Client) River() *river.River {
+	return c.rv
+}
+
+// Mock replaces registered services of the same types as services
+// for requests of c. See river.Overrides.
+func (c *Client) Mock(services ...interface{}) *Client {
+	for i := range services {
+		c.mocks = append(c.mocks, mock{service: services[i]})
+	}
+	return c
+}
+
+// MockAs replaces the service registered for the interface iface
+// points to for requests of c.
+func (c *Client) MockAs(iface, service interface{}) *Client {
+	c.mocks = append(c.mocks, mock{iface, service})
+	return c
+}
+
+// WithHeader sets a header for all requests of c.
+func (c *Client) WithHeader(key, value string) *Client {
+	c.header.Set(key, value)
+	return c
+}
+
+// Request creates a request with method for path p.
+// The request is sent on the first Do or Expect call.
+func (c *Client) Request(method, p string) *Request {
+	header := http.Header{}
+	for k, v := range c.header {
+		header[k] = append([]string(nil), v...)
+	}
+	return &Request{t: c.t, client: c, method: method, path: p, header: header,
+		mocks: append([]mock(nil), c.mocks...)}
+}
+
+// Call creates a GET request for handler h, called with a synthetic
+// Context without a River, routing or middlewares. URL parameters are
+// set with WithParam and services with Mock.
+//  rivertest.Call(t, getUser).WithParam("id", "1").Mock(store).ExpectJSON(user)
+func Call(t testing.TB, h river.Handler) *Request {
+	return &Request{t: t, handler: h, method: "GET", path: "/", header: http.Header{}}
+}
+
+// Get creates a GET request for path p.
+func (c *Client) Get(p string) *Request { return c.Request("GET", p) }
+
+// Post creates a POST request for path p.
+func (c *Client) Post(p string) *Request { return c.Request("POST", p) }
+
+// Put creates a PUT request for path p.
+func (c *Client) Put(p string) *Request { return c.Request("PUT", p) }
+
+// Patch creates a PATCH request for path p.
+func (c *Client) Patch(p string) *Request { return c.Request("PATCH", p) }
+
+// Delete creates a DELETE request for path p.
+func (c *Client) Delete(p string) *Request { return c.Request("DELETE", p) }
+
+// Request is a test request. Expect methods report
+// failures with Errorf of the test.
+type Request struct {
+	t            testing.TB
+	client       *Client
+	handler      river.Handler
+	method, path string
+	header       http.Header
+	query        url.Values
+	body         io.Reader
+	params       []string
+	mocks        []mock
+	response     *httptest.ResponseRecorder
+}
+
+// WithHeader sets a request header.
+func (r *Request) WithHeader(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// WithQuery adds a query parameter.
+func (r *Request) WithQuery(key, value string) *Request {
+	if r.query == nil {
+		r.query = url.Values{}
+	}
+	r.query.Add(key, value)
+	return r
+}
+
+// WithMethod sets the request method of a request created with Call.
+func (r *Request) WithMethod(method string) *Request {
+	r.method = method
+	return r
+}
+
+// WithParam sets URL parameter key to value for a request created with Call.
+func (r *Request) WithParam(key, value string) *Request {
+	r.params = append(r.params, key, value)
+	return r
+}
+
+// Mock replaces registered services of the same types as services
+// for the request.
+func (r *Request) Mock(services ...interface{}) *Request {
+	for i := range services {
+		r.mocks = append(r.mocks, mock{service: services[i]})
+	}
+	return r
+}
+
+// MockAs replaces the service registered for the interface iface
+// points to for the request.
+func (r *Request) MockAs(iface, service interface{}) *Request {
+	r.mocks = append(r.mocks, mock{iface, service})
+	return r
+}
+
+// WithBody sets the request body.
+func (r *Request) WithBody(body string) *Request {
+	r.body = strings.NewReader(body)
+	return r
+}
+
+// WithJSON sets the request body to v encoded as JSON.
+func (r *Request) WithJSON(v interface{}) *Request {
+	b, err := json.Marshal(v)
+	if err != nil {
+		r.t.Fatalf("rivertest: cannot encode request body: %v", err)
+	}
+	r.body = bytes.NewReader(b)
+	r.header.Set("Content-Type", "application/json")
+	return r
+}
+
+// Do sends the request, if not sent, and returns the response.
+func (r *Request) Do() *httptest.ResponseRecorder {
+	if r.response != nil {
+		return r.response
+	}
+	target := r.path
+	if r.query != nil {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + r.query.Encode()
+	}
+	req := httptest.NewRequest(r.method, target, r.body)
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	r.response = httptest.NewRecorder()
+	if r.handler != nil {
+		c := river.NewContext(r.response, req, r.params...)
+		for _, m := range r.mocks {
+			if m.iface != nil {
+				c.RegisterAs(m.iface, m.service)
+			} else {
+				c.Register(m.service)
+			}
+		}
+		c.Call(r.handler)
+		return r.response
+	}
+
+	overrides := &river.Overrides{}
+	for _, m := range r.mocks {
+		if m.iface != nil {
+			overrides.RegisterAs(m.iface, m.service)
+		} else {
+			overrides.Register(m.service)
+		}
+	}
+	r.client.rv.ServeHTTP(r.response, river.WithOverrides(req, overrides))
+	return r.response
+}
+
+// ExpectStatus expects the response status to be status.
+func (r *Request) ExpectStatus(status int) *Request {
+	r.t.Helper()
+	if found := r.Do().Code; found != status {
+		r.errorf("expected status %d, found %d", status, found)
+	}
+	return r
+}
+
+// ExpectHeader expects the response header key to be value.
+func (r *Request) ExpectHeader(key, value string) *Request {
+	r.t.Helper()
+	if found := r.Do().Header().Get(key); found != value {
+		r.errorf("expected header %s %q, found %q", key, value, found)
+	}
+	return r
+}
+
+// ExpectBody expects the response body to be body.
+func (r *Request) ExpectBody(body string) *Request {
+	r.t.Helper()
+	if found := r.Do().Body.String(); found != body {
+		r.errorf("expected body %q, found %q", body, found)
+	}
+	return r
+}
+
+// ExpectJSON expects the response body to be JSON equal to v
+// encoded as JSON.
+func (r *Request) ExpectJSON(v interface{}) *Request {
+	r.t.Helper()
+	var expected, found interface{}
+	b, err := json.Marshal(v)
+	if err == nil {
+		err = json.Unmarshal(b, &expected)
+	}
+	if err != nil {
+		r.errorf("cannot encode expected JSON: %v", err)
+		return r
+	}
+	body := r.Do().Body.Bytes()
+	if err := json.Unmarshal(body, &found); err != nil {
+		r.errorf("expected JSON body, found %q", body)
+		return r
+	}
+	if !reflect.DeepEqual(expected, found) {
+		r.errorf("expected JSON %s, found %s", b, bytes.TrimSpace(body))
+	}
+	return r
+}
+
+// DecodeJSON decodes the response body as JSON into v.
+func (r *Request) DecodeJSON(v interface{}) *Request {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Do().Body.Bytes(), v); err != nil {
+		r.errorf("cannot decode JSON body: %v", err)
+	}
+	return r
+}
+
+func (r *Request) errorf(format string, args ...interface{}) {
+	r.t.Helper()
+	r.t.Errorf("%s %s: "+format, append([]interface{}{r.method, r.path}, args...)...)
+}
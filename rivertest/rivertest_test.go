@@ -0,0 +1,108 @@
+package rivertest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/abiosoft/river"
+)
+
+type userStore struct {
+	prefix string
+}
+
+func (s userStore) Name(id string) string { return s.prefix + " " + id }
+
+type user struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func getUser(c *river.Context, store userStore) user {
+	return user{ID: c.Param("id"), Name: store.Name(c.Param("id"))}
+}
+
+// fakeT records test failures.
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestClient(t *testing.T) {
+	e := river.NewEndpoint().
+		Get("/:id", getUser).
+		Post("/", func(c *river.Context) (int, user, error) {
+			var u user
+			if err := c.DecodeJSONBody(&u); err != nil {
+				return 0, u, err
+			}
+			return 201, u, nil
+		})
+	e.Register(userStore{"db"})
+	rv := river.New().Handle("/user", e)
+
+	client := New(t, rv).WithHeader("X-Test", "1")
+	client.Get("/user/1").
+		ExpectStatus(200).
+		ExpectHeader("Content-Type", "application/json").
+		ExpectJSON(user{ID: "1", Name: "db 1"})
+	client.Post("/user").
+		WithJSON(river.M{"id": "2", "name": "River"}).
+		ExpectStatus(201).
+		ExpectJSON(river.M{"id": "2", "name": "River"})
+
+	var u user
+	client.Mock(userStore{"mock"}).Get("/user/3").DecodeJSON(&u)
+	if u.Name != "mock 3" {
+		t.Errorf("Expected mock 3, found %s", u.Name)
+	}
+	// mocks do not leak to other clients.
+	New(t, rv).Get("/user/3").ExpectJSON(user{ID: "3", Name: "db 3"})
+	New(t, rv).Get("/user/3").Mock(userStore{"request"}).ExpectJSON(user{ID: "3", Name: "request 3"})
+
+	f := &fakeT{}
+	New(f, rv).Get("/user/1").ExpectStatus(404).ExpectJSON(user{ID: "2"})
+	if len(f.errors) != 2 {
+		t.Errorf("Expected 2 failures, found %v", f.errors)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	NewHandler(t, "GET", "/user/:id", getUser).
+		Mock(userStore{"mock"}).
+		Get("/user/5").
+		WithQuery("fields", "name").
+		ExpectStatus(200).
+		ExpectJSON(user{ID: "5", Name: "mock 5"})
+}
+
+func TestCall(t *testing.T) {
+	Call(t, getUser).
+		WithParam("id", "7").
+		Mock(userStore{"mock"}).
+		ExpectStatus(200).
+		ExpectJSON(user{ID: "7", Name: "mock 7"})
+	Call(t, func(c *river.Context, n namer) string { return n.Name(c.Param("id")) }).
+		WithParam("id", "8").
+		MockAs((*namer)(nil), userStore{"mock"}).
+		ExpectJSON("mock 8")
+}
+
+type namer interface {
+	Name(id string) string
+}
+
+func TestMockAs(t *testing.T) {
+	e := river.NewEndpoint().Get("/:id", func(c *river.Context, n namer) string {
+		return n.Name(c.Param("id"))
+	})
+	e.RegisterAs((*namer)(nil), userStore{"db"})
+	NewEndpoint(t, e).Get("/1").ExpectJSON("db 1")
+	NewEndpoint(t, e).MockAs((*namer)(nil), userStore{"mock"}).Get("/1").ExpectJSON("mock 1")
+}
@@ -0,0 +1,115 @@
+package river
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// contextPool pools Contexts across requests.
+var contextPool = sync.Pool{
+	New: func() interface{} { return &Context{} },
+}
+
+// route handles requests for a route. Its middleware chain and service
+// layers are built on the first request and shared by requests.
+type route struct {
+	rv      *River
+	path    string
+	e       *Endpoint
+	handler Middleware
+
+	mu    sync.Mutex
+	built atomic.Value // *routeChain
+}
+
+// routeChain is the prebuilt middleware chain and service layers of a route.
+// It is not modified once built.
+type routeChain struct {
+	version     uint64
+	middlewares []Middleware
+	layers      []*serviceInjector
+}
+
+// chain returns the chain of rt, rebuilding it if stale.
+func (rt *route) chain() *routeChain {
+	version := atomic.LoadUint64(&rt.rv.version)
+	if ch, ok := rt.built.Load().(*routeChain); ok && ch.version == version {
+		return ch
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if ch, ok := rt.built.Load().(*routeChain); ok && ch.version == version {
+		return ch
+	}
+	ch := &routeChain{
+		version:     version,
+		middlewares: composeMiddlewares(rt.rv, rt.handler, rt.e),
+		layers:      rt.rv.layers(rt.e),
+	}
+	rt.built.Store(ch)
+	return ch
+}
+
+func (rt *route) serve(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	ch := rt.chain()
+	c := contextPool.Get().(*Context)
+	c.rw = w
+	c.Request = r
+	c.params = p
+	c.route = rt.path
+	c.renderer = notNilRenderer(rt.e.inheritedRenderer(), rt.rv.renderer)
+	c.errHandler = rt.rv.errHandler
+	c.onError = rt.rv.onError
+	c.middlewares = ch.middlewares
+	c.cache = rt.rv.cachePolicy(rt.e)
+	if o, ok := r.Context().Value(overridesKey{}).(*Overrides); ok {
+		c.injector.reset(append([]*serviceInjector{&o.services}, ch.layers...))
+	} else {
+		c.injector.reset(ch.layers)
+	}
+	defer c.release()
+
+	c.initRequestID()
+	c.Next()
+}
+
+// release disposes request services of c and returns c to the pool.
+func (c *Context) release() {
+	c.dispose()
+	services, cleanups, creating := c.serviceInjector, c.disposer.cleanups, c.creating
+	*c = Context{}
+	c.injector.serviceInjector, c.disposer.cleanups, c.creating = services, cleanups, creating
+	c.injector.reset(nil)
+	contextPool.Put(c)
+}
+
+// chainChanged rebuilds the middleware chains of routes of rv
+// on their next request.
+func (rv *River) chainChanged() {
+	atomic.AddUint64(&rv.version, 1)
+}
+
+// chainChanged rebuilds the middleware chains of routes of e
+// and endpoints mounted on e on their next request.
+func (e *Endpoint) chainChanged() {
+	for _, m := range e.mounts {
+		m.rv.chainChanged()
+	}
+	for _, child := range e.children {
+		child.endpoint.chainChanged()
+	}
+}
+
+// layers returns the service layers for requests of e, in order of precedence:
+// e and its ancestors, then rv.
+func (rv *River) layers(e *Endpoint) []*serviceInjector {
+	var layers []*serviceInjector
+	for p := e; p != nil; p = p.parent {
+		layers = append(layers, &p.serviceInjector)
+	}
+	return append(layers, &rv.serviceInjector)
+}
@@ -0,0 +1,124 @@
+package river
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRoute_Use(t *testing.T) {
+	var trace []string
+	mid := func(name string) Middleware {
+		return func(c *Context) {
+			trace = append(trace, name)
+			c.Next()
+		}
+	}
+
+	rv := New(mid("a"))
+	rv.AccessLog(AccessLog{Disabled: true})
+	e := NewEndpoint().Get("/", func() string { return "ok" })
+	e.Use(mid("e"))
+	rv.Handle("/", e)
+
+	tests := []struct {
+		use   func()
+		trace string
+	}{
+		{func() {}, "a,e"},
+		{func() { rv.Use(mid("b")) }, "a,b,e"},
+		{func() { e.Use(mid("f")) }, "a,b,e,f"},
+	}
+	for i, test := range tests {
+		test.use()
+		trace = nil
+		rv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		if s := strings.Join(trace, ","); s != test.trace {
+			t.Errorf("Test %d: expected middlewares %s, found %s", i, test.trace, s)
+		}
+	}
+}
+
+func TestRoute_concurrent(t *testing.T) {
+	type id string
+	type session struct{ id id }
+
+	rv := New()
+	rv.AccessLog(AccessLog{Disabled: true})
+	rv.Provide(func(i id) *session { return &session{i} }, RequestScope)
+	e := NewEndpoint().Get("/:id", func(c *Context, i id, s *session) string {
+		return string(i) + "/" + string(s.id) + "/" + c.Param("id")
+	})
+	e.Use(func(c *Context) {
+		c.Register(id(c.Param("id")))
+		c.Next()
+	})
+	e.Renderer(PlainRenderer)
+	rv.Handle("/", e)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				p := string(rune('a'+i)) + string(rune('a'+j%26))
+				w := httptest.NewRecorder()
+				rv.ServeHTTP(w, httptest.NewRequest("GET", "/"+p, nil))
+				if expected := p + "/" + p + "/" + p; w.Body.String() != expected {
+					t.Errorf("Test %d: expected %s, found %s", i, expected, w.Body.String())
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRoute_versions(t *testing.T) {
+	rv, other := New(), New()
+	group := rv.Group("/org")
+	project := NewEndpoint().Get("/", func() {})
+	group.Mount("/project", project)
+
+	tests := []struct {
+		change  func()
+		changed bool
+	}{
+		{func() { other.Use(func(c *Context) {}) }, false},
+		{func() { other.CORS(CORS{}) }, false},
+		{func() { NewEndpoint().Use(func(c *Context) {}) }, false},
+		{func() { rv.Use(func(c *Context) {}) }, true},
+		{func() { group.Use(func(c *Context) {}) }, true},
+		{func() { project.CORS(CORS{}) }, true},
+		{func() { rv.AccessLog(AccessLog{}) }, true},
+	}
+	for i, test := range tests {
+		version := rv.version
+		test.change()
+		if changed := rv.version != version; changed != test.changed {
+			t.Errorf("Test %d: expected changed %v, found %v", i, test.changed, changed)
+		}
+	}
+}
+
+func TestRoute_escapedContext(t *testing.T) {
+	contexts := make(map[string]context.Context)
+	rv := New()
+	rv.AccessLog(AccessLog{Disabled: true})
+	rv.Handle("/", NewEndpoint().Get("/:user", func(c *Context) {
+		c.Set("user", c.Param("user"))
+		contexts[c.Param("user")] = c.Request.Context()
+	}))
+
+	for _, user := range []string{"alice", "bob"} {
+		rv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/"+user, nil))
+	}
+	for user, ctx := range contexts {
+		if v := ctx.Value("user"); v != user {
+			t.Errorf("Expected %s, found %v", user, v)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package river
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// lifecycle holds the server state of a River.
+type lifecycle struct {
+	mu              sync.Mutex
+	server          *http.Server
+	redirect        *http.Server
+	tlsConfig       *tls.Config
+	redirectAddr    string
+	stopped         chan struct{}
+	stopErr         error
+	shutdown        bool
+	shutdownOnce    sync.Once
+	onStart         []func()
+	onShutdown      []func()
+	signals         []os.Signal
+	shutdownTimeout time.Duration
+}
+
+// done returns a channel that is closed when shutdown completes.
+func (l *lifecycle) done() chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stopped == nil {
+		l.stopped = make(chan struct{})
+	}
+	return l.stopped
+}
+
+// Run starts River as an http server.
+// Run blocks until the server stops. If the server is stopped with
+// Shutdown or by a signal set with GracefulShutdown, Run returns after
+// in-flight requests have been drained.
+//
+// Run returns http.ErrServerClosed if Shutdown has been called.
+func (rv *River) Run(addr string) error {
+	return rv.serve(&http.Server{Addr: addr, Handler: rv}, (*http.Server).Serve)
+}
+
+// serve listens on the address of s and serves with serve. The server
+// is started, and OnStart hooks are called, once the listener is up.
+func (rv *River) serve(s *http.Server, serve func(*http.Server, net.Listener) error) error {
+	if err := rv.Validate(); err != nil {
+		log.printf("Invalid handler dependencies:\n%v", err)
+		return err
+	}
+
+	addr := s.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	rv.life.mu.Lock()
+	if rv.life.shutdown {
+		rv.life.mu.Unlock()
+		ln.Close()
+		return http.ErrServerClosed
+	}
+	rv.life.server = s
+	rv.life.mu.Unlock()
+
+	var sig chan os.Signal
+	if len(rv.life.signals) > 0 {
+		sig = make(chan os.Signal, 1)
+		signal.Notify(sig, rv.life.signals...)
+		defer signal.Stop(sig)
+	}
+
+	log.printf("Server started on %s", ln.Addr())
+	rv.Dump()
+	for _, f := range rv.life.onStart {
+		f()
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- serve(s, ln) }()
+
+	select {
+	case err := <-errc:
+		if err != http.ErrServerClosed {
+			rv.stopRedirect()
+			return err
+		}
+		// Shutdown has been called, wait for it to drain requests.
+		<-rv.life.done()
+		rv.stopRedirect()
+		return rv.life.stopErr
+	case sg := <-sig:
+		log.printf("Received %v, shutting down", sg)
+		ctx := context.Background()
+		if rv.life.shutdownTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, rv.life.shutdownTimeout)
+			defer cancel()
+		}
+		return rv.Shutdown(ctx)
+	}
+}
+
+// Shutdown gracefully stops the server. It stops accepting new
+// connections and waits for in-flight requests to complete until ctx
+// is done. OnShutdown hooks are then called and registered services
+// that implement io.Closer are closed.
+//
+// Successive calls to Shutdown have no effect and return the
+// result of the first call. Run cannot be called after Shutdown.
+func (rv *River) Shutdown(ctx context.Context) error {
+	done := rv.life.done()
+	rv.life.shutdownOnce.Do(func() {
+		rv.life.mu.Lock()
+		rv.life.shutdown = true
+		s, redirect := rv.life.server, rv.life.redirect
+		rv.life.mu.Unlock()
+
+		if redirect != nil {
+			redirect.Shutdown(ctx)
+		}
+		if s != nil {
+			rv.life.stopErr = s.Shutdown(ctx)
+		}
+		for _, f := range rv.life.onShutdown {
+			f()
+		}
+		rv.closeServices()
+		log.println("Server stopped")
+		close(done)
+	})
+	<-done
+	return rv.life.stopErr
+}
+
+// GracefulShutdown makes Run listen for signals and shutdown the server
+// when any of them is received. In-flight requests are given up to
+// timeout to complete, a timeout of 0 waits indefinitely.
+//
+// signals defaults to SIGINT and SIGTERM.
+func (rv *River) GracefulShutdown(timeout time.Duration, signals ...os.Signal) *River {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	rv.life.signals = signals
+	rv.life.shutdownTimeout = timeout
+	return rv
+}
+
+// OnStart adds f to the functions called when the server starts.
+func (rv *River) OnStart(f func()) *River {
+	rv.life.onStart = append(rv.life.onStart, f)
+	return rv
+}
+
+// OnShutdown adds f to the functions called when the server stops,
+// after in-flight requests have been drained.
+func (rv *River) OnShutdown(f func()) *River {
+	rv.life.onShutdown = append(rv.life.onShutdown, f)
+	return rv
+}
+
+// closeServices closes global and endpoint services that implement
+// io.Closer. A service registered more than once is closed once.
+func (rv *River) closeServices() {
+	closed := make(map[io.Closer]bool)
+	injectors := []serviceInjector{rv.serviceInjector}
+	for _, e := range rv.endpoints {
+		injectors = append(injectors, e.serviceInjector)
+	}
+	for _, s := range injectors {
+		for _, service := range s {
+			if p, ok := service.(*provider); ok {
+				if p.scope == Singleton {
+					p.dispose()
+				}
+				continue
+			}
+			closer, ok := service.(io.Closer)
+			if !ok {
+				continue
+			}
+			if reflect.TypeOf(closer).Comparable() {
+				if closed[closer] {
+					continue
+				}
+				closed[closer] = true
+			}
+			if err := closer.Close(); err != nil {
+				log.printf("Error closing %T: %v", closer, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package river
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type closer struct{ closed int }
+
+func (c *closer) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestRiver_Shutdown(t *testing.T) {
+	var started, stopped bool
+	c := &closer{}
+
+	rv := New()
+	rv.Register(c)
+	e := NewEndpoint().Get("/", func() {})
+	e.Register(c)
+	rv.Handle("/", e).
+		OnStart(func() { started = true }).
+		OnShutdown(func() { stopped = true })
+
+	errc := make(chan error, 1)
+	go func() { errc <- rv.Run("127.0.0.1:0") }()
+
+	time.Sleep(50 * time.Millisecond)
+	if err := rv.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("Run returned %v, expected nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after Shutdown")
+	}
+
+	if !started || !stopped {
+		t.Errorf("hooks not called, started: %v, stopped: %v", started, stopped)
+	}
+	if c.closed != 1 {
+		t.Errorf("expected service to be closed once, closed %d times", c.closed)
+	}
+}
+
+func TestRiver_RunErrors(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var started bool
+	rv := New().OnStart(func() { started = true })
+	if err := rv.Run(ln.Addr().String()); err == nil {
+		t.Error("Expected error for address in use")
+	}
+	if started {
+		t.Error("Expected OnStart not to be called when listen fails")
+	}
+
+	rv.Shutdown(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- rv.Run("127.0.0.1:0") }()
+	select {
+	case err := <-errc:
+		if err != http.ErrServerClosed {
+			t.Errorf("Expected %v running after Shutdown, found %v", http.ErrServerClosed, err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Run did not return after Shutdown")
+	}
+	if started {
+		t.Error("Expected OnStart not to be called after Shutdown")
+	}
+}
@@ -1,6 +1,13 @@
 package river
 
-import "reflect"
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
 
 type serviceInjector map[reflect.Type]interface{}
 
@@ -15,6 +22,28 @@ func (s *serviceInjector) Register(service interface{}) {
 	s.register(service)
 }
 
+// RegisterAs registers service as the implementation of the interface
+// iface points to. Handlers with a parameter of the interface type
+// receive service.
+//  rv.RegisterAs((*UserStore)(nil), &PostgresStore{})
+//
+// Services are also resolved for an interface parameter if exactly
+// one registered service implements it, RegisterAs is only required
+// if there are more.
+func (s *serviceInjector) RegisterAs(iface, service interface{}) {
+	t := reflect.TypeOf(iface)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+		panic(fmt.Sprintf("river: %v is not a pointer to an interface e.g. (*Store)(nil)", t))
+	}
+	if st := reflect.TypeOf(service); st == nil || !st.Implements(t.Elem()) {
+		panic(fmt.Sprintf("river: %v does not implement %v", st, t.Elem()))
+	}
+	if *s == nil {
+		*s = make(serviceInjector)
+	}
+	(*s)[t.Elem()] = service
+}
+
 func (s *serviceInjector) register(service interface{}) {
 	if *s == nil {
 		*s = make(serviceInjector)
@@ -22,25 +51,65 @@ func (s *serviceInjector) register(service interface{}) {
 	(*s)[reflect.TypeOf(service)] = service
 }
 
-// invoke invokes function f. f must be Func type.
-func (s serviceInjector) invoke(f interface{}) {
-	if reflect.TypeOf(f).Kind() != reflect.Func {
+// Overrides are services that overrule services of the same type
+// registered on a River and its endpoints for a request, e.g. to
+// replace services with mocks in tests. See package rivertest.
+type Overrides struct {
+	services serviceInjector
+}
+
+// Register registers an overriding service.
+func (o *Overrides) Register(service interface{}) {
+	o.services.Register(service)
+}
+
+// RegisterAs registers an overriding service for the interface iface points to.
+func (o *Overrides) RegisterAs(iface, service interface{}) {
+	o.services.RegisterAs(iface, service)
+}
+
+type overridesKey struct{}
+
+// WithOverrides returns a shallow copy of r with its context changed
+// to one with o. Services registered by middlewares still take
+// precedence. o must not be modified while r is being served.
+func WithOverrides(r *http.Request, o *Overrides) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), overridesKey{}, o))
+}
+
+// resolver resolves a service by type.
+type resolver func(t reflect.Type) (reflect.Value, error)
+
+// invoke invokes function f and returns its results. f must be Func type.
+// Arguments are resolved from s, zero values are used for unregistered
+// types. An error is returned if a provider fails.
+func (s serviceInjector) invoke(f interface{}) ([]reflect.Value, error) {
+	in := &injector{layers: []*serviceInjector{&s}}
+	var r resolver
+	r = func(t reflect.Type) (reflect.Value, error) {
+		return in.resolve(t, r)
+	}
+	return call(f, r)
+}
+
+// call calls function f with arguments resolved by r.
+func call(f interface{}, r resolver) ([]reflect.Value, error) {
+	t := reflect.TypeOf(f)
+	if t.Kind() != reflect.Func {
 		// log and return to prevent panic.
 		log.println("Cannot invoke non function type")
-		return
+		return nil, nil
 	}
 
-	args := make([]reflect.Value, reflect.TypeOf(f).NumIn())
+	args := make([]reflect.Value, t.NumIn())
 	for i := range args {
-		argType := reflect.TypeOf(f).In(i)
-		if service, ok := s[argType]; ok {
-			args[i] = reflect.ValueOf(service)
-		} else {
-			// set zero value
-			args[i] = reflect.Zero(argType)
+		v, err := r(t.In(i))
+		if err != nil {
+			return nil, err
 		}
+		args[i] = v
 	}
-	reflect.ValueOf(f).Call(args)
+	return reflect.ValueOf(f).Call(args), nil
 }
 
 func copyInjectors(injectors ...serviceInjector) serviceInjector {
@@ -52,3 +121,122 @@ func copyInjectors(injectors ...serviceInjector) serviceInjector {
 	}
 	return s
 }
+
+// implementation returns the type of the only service in s, or of
+// builtins, that implements interface t, nil if there is none or an
+// error if there are more.
+func (s serviceInjector) implementation(t reflect.Type, builtins ...reflect.Type) (reflect.Type, error) {
+	return implementation(t, []serviceInjector{s}, builtins)
+}
+
+// implementation is like serviceInjector.implementation for services of
+// layers. A service shadowed by one of the same type in a preceding
+// layer is skipped.
+func implementation(t reflect.Type, layers []serviceInjector, builtins []reflect.Type) (reflect.Type, error) {
+	var types []reflect.Type
+	var services []interface{}
+	for _, b := range builtins {
+		if b.Implements(t) {
+			types = append(types, b)
+			services = append(services, b)
+		}
+	}
+	for i, s := range layers {
+	next:
+		for k, service := range s {
+			if !k.Implements(t) || shadowed(layers[:i], k) {
+				continue
+			}
+			for j := range services {
+				if sameService(services[j], service) {
+					continue next
+				}
+			}
+			types = append(types, k)
+			services = append(services, service)
+		}
+	}
+
+	switch len(types) {
+	case 0:
+		return nil, nil
+	case 1:
+		return types[0], nil
+	}
+	names := make([]string, len(types))
+	for i := range types {
+		names[i] = types[i].String()
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("ambiguous service %v, implemented by %s; use RegisterAs to choose one",
+		t, strings.Join(names, ", "))
+}
+
+func shadowed(layers []serviceInjector, t reflect.Type) bool {
+	for _, s := range layers {
+		if _, ok := s[t]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sameService checks if a and b are the same service
+// registered with different types.
+func sameService(a, b interface{}) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	return ta == tb && ta.Comparable() && a == b
+}
+
+// injector holds the services of a request. Services registered during
+// the request are looked up first, followed by layers in order of
+// precedence. Layers are shared by requests and must not be modified.
+type injector struct {
+	serviceInjector
+	layers   []*serviceInjector
+	disposer disposer
+	// creating are the types of the providers being created.
+	creating []reflect.Type
+	// singleton is the type of the singleton whose dependencies
+	// are resolved by the injector, if any.
+	singleton reflect.Type
+}
+
+// lookup returns the service registered for t.
+func (in *injector) lookup(t reflect.Type) (interface{}, bool) {
+	service, _, ok := in.find(t)
+	return service, ok
+}
+
+// find returns the service registered for t and the index of its layer,
+// -1 for request services.
+func (in *injector) find(t reflect.Type) (interface{}, int, bool) {
+	if service, ok := in.serviceInjector[t]; ok {
+		return service, -1, true
+	}
+	for i, s := range in.layers {
+		if service, ok := (*s)[t]; ok {
+			return service, i, true
+		}
+	}
+	return nil, 0, false
+}
+
+// implementation is like serviceInjector.implementation for all services of in.
+func (in *injector) implementation(t reflect.Type, builtins ...reflect.Type) (reflect.Type, error) {
+	layers := make([]serviceInjector, 0, len(in.layers)+1)
+	layers = append(layers, in.serviceInjector)
+	for _, s := range in.layers {
+		layers = append(layers, *s)
+	}
+	return implementation(t, layers, builtins)
+}
+
+// reset removes request services and sets the layers of in.
+func (in *injector) reset(layers []*serviceInjector) {
+	for t := range in.serviceInjector {
+		delete(in.serviceInjector, t)
+	}
+	in.layers = layers
+	in.creating = in.creating[:0]
+}
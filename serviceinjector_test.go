@@ -41,3 +41,72 @@ func TestServiceInjector_merge(t *testing.T) {
 		t.Error("injector should include string")
 	}
 }
+
+type store interface {
+	Name() string
+}
+
+type memStore struct{ name string }
+
+func (m *memStore) Name() string { return m.name }
+
+type fileStore struct{}
+
+func (fileStore) Name() string { return "file" }
+
+func TestServiceInjector_interfaces(t *testing.T) {
+	mem := &memStore{"mem"}
+	var exact, auto, ambiguous serviceInjector
+	exact.Register(mem)
+	exact.Register(fileStore{})
+	exact.RegisterAs((*store)(nil), fileStore{})
+	auto.Register(mem)
+	ambiguous.Register(mem)
+	ambiguous.Register(fileStore{})
+
+	tests := []struct {
+		injector serviceInjector
+		expected string
+		err      bool
+	}{
+		{exact, "file", false},
+		{auto, "mem", false},
+		{ambiguous, "", true},
+		{serviceInjector{}, "", false},
+	}
+	for i, test := range tests {
+		var name string
+		_, err := test.injector.invoke(func(s store) {
+			if s != nil {
+				name = s.Name()
+			}
+		})
+		if (err != nil) != test.err {
+			t.Errorf("Test %d: expected error %v, found %v", i, test.err, err)
+		}
+		if name != test.expected {
+			t.Errorf("Test %d: expected %q, found %q", i, test.expected, name)
+		}
+	}
+}
+
+func TestServiceInjector_RegisterAsInvalid(t *testing.T) {
+	tests := []struct {
+		iface, service interface{}
+	}{
+		{(*store)(nil), "not a store"},
+		{store(nil), &memStore{}},
+		{(*memStore)(nil), &memStore{}},
+	}
+	for i, test := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Test %d: expected panic", i)
+				}
+			}()
+			var s serviceInjector
+			s.RegisterAs(test.iface, test.service)
+		}()
+	}
+}
@@ -0,0 +1,229 @@
+package river
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventStream is a Server-Sent Events stream created with Context.SSE.
+// Methods of EventStream are safe for concurrent use.
+type EventStream struct {
+	c        *Context
+	request  *http.Request
+	renderer Renderer
+	done     <-chan struct{}
+	ctxErr   func() error
+
+	mu   sync.Mutex
+	err  error
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+// SSE starts a Server-Sent Events stream. The response header is written
+// and events are sent to the client as they are sent on the stream.
+// The stream is closed when the handler returns.
+//  stream := c.SSE().Heartbeat(15 * time.Second)
+//  for {
+//    select {
+//    case <-stream.Done():
+//      return
+//    case n := <-notifications:
+//      stream.Send("notification", n.ID, n)
+//    }
+//  }
+//
+// The ResponseWriter must implement http.Flusher for events to be
+// sent immediately.
+func (c *Context) SSE() *EventStream {
+	ctx := c.context()
+	// event data is not negotiated, the client accepts text/event-stream.
+	request := new(http.Request)
+	*request = *c.Request
+	request.Header = c.Request.Header.Clone()
+	request.Header.Del("Accept")
+	s := &EventStream{
+		c:        c,
+		request:  request,
+		renderer: c.renderer,
+		done:     ctx.Done(),
+		ctxErr:   ctx.Err,
+		stop:     make(chan struct{}),
+	}
+	h := c.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// disable proxy buffering e.g. nginx.
+	h.Set("X-Accel-Buffering", "no")
+	h.Del("Content-Length")
+	if !c.headerWritten {
+		c.WriteHeader(http.StatusOK)
+	}
+	c.Flush()
+	c.disposer.add(s.Close)
+	return s
+}
+
+// Flush sends buffered data to the client. It implements http.Flusher.
+// Flush has no effect if the ResponseWriter is not an http.Flusher.
+func (c *Context) Flush() {
+	if !c.headerWritten {
+		c.WriteHeader(http.StatusOK)
+	}
+	if f, ok := c.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// LastEventID returns the Last-Event-ID header sent by a reconnecting
+// client, the id of the last event it received. Empty string is
+// returned for new clients.
+func (s *EventStream) LastEventID() string {
+	return s.request.Header.Get("Last-Event-ID")
+}
+
+// Done returns a channel that's closed when the client disconnects.
+func (s *EventStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Send sends an event with data. event and id are omitted if empty.
+// Strings and byte slices are sent as is, other data is encoded
+// with the Renderer of the request, ignoring the Accept header.
+//
+// An error is returned if the client has disconnected, event or id
+// contain line breaks or data cannot be encoded.
+func (s *EventStream) Send(event, id string, data interface{}) error {
+	if strings.ContainsAny(event, "\r\n") || strings.ContainsAny(id, "\r\n") {
+		return errEventField
+	}
+	s.mu.Lock()
+	err := s.err
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	b, err := s.encode(data)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if id != "" {
+		writeField(&buf, "id", id)
+	}
+	if event != "" {
+		writeField(&buf, "event", event)
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		writeField(&buf, "data", strings.TrimSuffix(line, "\r"))
+	}
+	buf.WriteByte('\n')
+	return s.write(buf.Bytes())
+}
+
+// Retry sets the time a client waits before reconnecting after
+// the connection is lost.
+func (s *EventStream) Retry(d time.Duration) error {
+	return s.write([]byte("retry: " + strconv.FormatInt(int64(d/time.Millisecond), 10) + "\n\n"))
+}
+
+// Heartbeat sends a comment every interval to keep the connection
+// open through proxies and detect disconnected clients.
+func (s *EventStream) Heartbeat(interval time.Duration) *EventStream {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-s.done:
+				return
+			case <-ticker.C:
+				if s.write([]byte(":\n\n")) != nil {
+					return
+				}
+			}
+		}
+	}()
+	return s
+}
+
+// Close stops heartbeats. No events can be sent after Close.
+// Close is called when the handler returns.
+func (s *EventStream) Close() {
+	s.once.Do(func() {
+		close(s.stop)
+		s.wg.Wait()
+		s.mu.Lock()
+		if s.err == nil {
+			s.err = errStreamClosed
+		}
+		s.mu.Unlock()
+	})
+}
+
+var (
+	errStreamClosed = NewError(http.StatusGone, "Event stream is closed")
+	errEventField   = NewError(http.StatusInternalServerError, "Event and id cannot contain line breaks")
+)
+
+func (s *EventStream) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.ctxErr(); err != nil {
+		s.err = err
+		return err
+	}
+	if _, err := s.c.Write(b); err != nil {
+		s.err = err
+		return err
+	}
+	s.c.Flush()
+	return nil
+}
+
+// encode returns data encoded for an event. data is rendered
+// to a buffer, not to the Context of the stream.
+func (s *EventStream) encode(data interface{}) ([]byte, error) {
+	switch d := data.(type) {
+	case string:
+		return []byte(d), nil
+	case []byte:
+		return d, nil
+	}
+
+	b := &eventBuffer{header: http.Header{}}
+	err := s.renderer(&Context{Request: s.request, rw: b}, data)
+	return bytes.TrimRight(b.Bytes(), "\n"), err
+}
+
+func writeField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// eventBuffer buffers the encoding of event data. Headers set
+// by the renderer are discarded.
+type eventBuffer struct {
+	header http.Header
+	bytes.Buffer
+}
+
+func (e *eventBuffer) Header() http.Header {
+	return e.header
+}
+
+func (e *eventBuffer) WriteHeader(int) {}
@@ -0,0 +1,113 @@
+package river
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSE(t *testing.T) {
+	var sendErr error
+	var fieldErrs []error
+	var late *EventStream
+	rv := New()
+	rv.AccessLog(AccessLog{Disabled: true})
+	rv.Handle("/", NewEndpoint().
+		Get("/events", func(c *Context) {
+			stream := c.SSE()
+			stream.Retry(time.Second)
+			stream.Send("", "", "hello")
+			stream.Send("user", "2", M{"name": "river"})
+			stream.Send("resume", "", stream.LastEventID())
+			stream.Send("", "", "a\nb")
+		}).
+		Get("/fields", func(c *Context) {
+			stream := c.SSE()
+			fieldErrs = append(fieldErrs,
+				stream.Send("a\ndata: injected", "", "x"),
+				stream.Send("", "1\r2", "x"))
+		}).
+		Get("/heartbeat", func(c *Context) {
+			c.SSE().Heartbeat(time.Millisecond)
+			time.Sleep(20 * time.Millisecond)
+		}).
+		Get("/late", func(c *Context) {
+			late = c.SSE()
+		}).
+		Get("/closed", func(c *Context) {
+			disconnect := c.WithCancel()
+			stream := c.SSE()
+			disconnect()
+			sendErr = stream.Send("", "", "lost")
+		}))
+
+	tests := []struct {
+		path, lastEventID, body string
+	}{
+		{"/events", "1", "retry: 1000\n\ndata: hello\n\nid: 2\nevent: user\ndata: {\"name\":\"river\"}\n\nevent: resume\ndata: 1\n\ndata: a\ndata: b\n\n"},
+		{"/closed", "", ""},
+		{"/fields", "", ""},
+	}
+	for i, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", test.path, nil)
+		if test.lastEventID != "" {
+			r.Header.Set("Last-Event-ID", test.lastEventID)
+		}
+		rv.ServeHTTP(w, r)
+		if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("Test %d: expected Content-Type text/event-stream, found %s", i, ct)
+		}
+		if !w.Flushed {
+			t.Errorf("Test %d: expected response to be flushed", i)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("Test %d: expected body %q, found %q", i, test.body, w.Body.String())
+		}
+	}
+	if len(fieldErrs) != 2 {
+		t.Errorf("Expected 2 errors for line breaks, found %d", len(fieldErrs))
+	}
+	for i, err := range fieldErrs {
+		if err != errEventField {
+			t.Errorf("Test %d: expected %v for line breaks, found %v", i, errEventField, err)
+		}
+	}
+	if sendErr != context.Canceled {
+		t.Errorf("Expected %v sending to disconnected client, found %v", context.Canceled, sendErr)
+	}
+
+	w := httptest.NewRecorder()
+	rv.ServeHTTP(w, httptest.NewRequest("GET", "/heartbeat", nil))
+	if !strings.HasPrefix(w.Body.String(), ":\n\n") {
+		t.Errorf("Expected heartbeats, found %q", w.Body.String())
+	}
+
+	// sending after the handler returns must not touch the recycled Context.
+	rv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/late", nil))
+	done := make(chan error)
+	go func() { done <- late.Send("", "", M{"late": true}) }()
+	rv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/events", nil))
+	if err := <-done; err != errStreamClosed {
+		t.Errorf("Expected %v sending after the handler returns, found %v", errStreamClosed, err)
+	}
+
+	// event data is encoded with the default of negotiated renderers.
+	negotiated := New().Renderer(Negotiate(
+		MediaRenderer{"application/json", JSONRenderer},
+		MediaRenderer{"application/xml", XMLRenderer},
+	))
+	negotiated.AccessLog(AccessLog{Disabled: true})
+	negotiated.Handle("/", NewEndpoint().Get("/events", func(c *Context) {
+		c.SSE().Send("", "", M{"name": "river"})
+	}))
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", nil)
+	r.Header.Set("Accept", "text/event-stream")
+	negotiated.ServeHTTP(w, r)
+	if body := "data: {\"name\":\"river\"}\n\n"; w.Body.String() != body {
+		t.Errorf("Expected negotiated body %q, found %q", body, w.Body.String())
+	}
+}
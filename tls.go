@@ -0,0 +1,158 @@
+package river
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RunTLS starts River as an https server with certFile and keyFile.
+// certFile and keyFile can be empty if certificates are provided with
+// TLSConfig or DevCertificate.
+//
+// If RedirectHTTP is set, an http server that redirects to https
+// is started alongside once the https server is listening.
+func (rv *River) RunTLS(addr, certFile, keyFile string) error {
+	// fail before listening if certificates are invalid.
+	if certFile != "" || keyFile != "" {
+		if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+			return err
+		}
+	} else if c := rv.life.tlsConfig; c == nil ||
+		(len(c.Certificates) == 0 && c.GetCertificate == nil && c.GetConfigForClient == nil) {
+		return errors.New("river: RunTLS requires certFile and keyFile or certificates in TLSConfig")
+	}
+
+	s := &http.Server{Addr: addr, Handler: rv, TLSConfig: rv.life.tlsConfig}
+	return rv.serve(s, func(s *http.Server, ln net.Listener) error {
+		if rv.life.redirectAddr != "" {
+			rv.startRedirect(ln.Addr().String())
+		}
+		return s.ServeTLS(ln, certFile, keyFile)
+	})
+}
+
+// TLSConfig sets the tls configuration used by RunTLS.
+func (rv *River) TLSConfig(c *tls.Config) *River {
+	rv.life.tlsConfig = c
+	return rv
+}
+
+// DevCertificate generates an in-memory self-signed certificate for
+// hosts and adds it to the tls configuration. hosts defaults to
+// localhost and 127.0.0.1.
+//
+// This is meant for development, browsers and clients will not
+// trust the certificate.
+func (rv *River) DevCertificate(hosts ...string) *River {
+	cert, err := selfSignedCertificate(hosts...)
+	if err != nil {
+		// generating a key only fails if the system random source fails.
+		panic(err)
+	}
+	if rv.life.tlsConfig == nil {
+		rv.life.tlsConfig = &tls.Config{}
+	}
+	rv.life.tlsConfig.Certificates = append(rv.life.tlsConfig.Certificates, cert)
+	return rv
+}
+
+// RedirectHTTP starts an http server on addr alongside RunTLS that
+// redirects all requests to https.
+func (rv *River) RedirectHTTP(addr string) *River {
+	rv.life.redirectAddr = addr
+	return rv
+}
+
+// startRedirect starts the redirect server, unless Shutdown has been called.
+func (rv *River) startRedirect(tlsAddr string) {
+	s := &http.Server{Addr: rv.life.redirectAddr, Handler: httpsRedirect(tlsAddr)}
+	rv.life.mu.Lock()
+	if rv.life.shutdown {
+		rv.life.mu.Unlock()
+		return
+	}
+	rv.life.redirect = s
+	rv.life.mu.Unlock()
+
+	log.printf("Redirecting http on %s to https", s.Addr)
+	go func() {
+		if err := s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.printf("Redirect server error: %v", err)
+		}
+	}()
+}
+
+// stopRedirect closes the redirect server, if started.
+func (rv *River) stopRedirect() {
+	rv.life.mu.Lock()
+	redirect := rv.life.redirect
+	rv.life.redirect = nil
+	rv.life.mu.Unlock()
+	if redirect != nil {
+		redirect.Close()
+	}
+}
+
+// httpsRedirect redirects requests to the same host on the port of tlsAddr.
+func httpsRedirect(tlsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		u := *r.URL
+		u.Scheme = "https"
+		u.Host = host
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+	})
+}
+
+func selfSignedCertificate(hosts ...string) (tls.Certificate, error) {
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1"}
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"River Development"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,101 @@
+package river
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDevCertificate(t *testing.T) {
+	rv := New().DevCertificate("example.com", "10.0.0.1")
+	certs := rv.life.tlsConfig.Certificates
+	if len(certs) != 1 {
+		t.Fatalf("expected 1 certificate, found %d", len(certs))
+	}
+	cert, err := x509.ParseCertificate(certs[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.VerifyHostname("example.com"); err != nil {
+		t.Error(err)
+	}
+	if err := cert.VerifyHostname("10.0.0.1"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		tlsAddr, url, expected string
+	}{
+		{":443", "http://example.com/user?id=1", "https://example.com/user?id=1"},
+		{":8443", "http://example.com:8080/user", "https://example.com:8443/user"},
+	}
+	for i, test := range tests {
+		w := httptest.NewRecorder()
+		httpsRedirect(test.tlsAddr).ServeHTTP(w, httptest.NewRequest("GET", test.url, nil))
+		if w.Code != http.StatusMovedPermanently {
+			t.Errorf("Test %d: expected status %d, found %d", i, http.StatusMovedPermanently, w.Code)
+		}
+		if loc := w.Header().Get("Location"); loc != test.expected {
+			t.Errorf("Test %d: expected %s, found %s", i, test.expected, loc)
+		}
+	}
+}
+
+func TestRunTLSErrors(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirectAddr := ln.Addr().String()
+	ln.Close()
+
+	tests := []struct {
+		rv                *River
+		certFile, keyFile string
+	}{
+		{New(), "missing.pem", "missing.key"},
+		{New(), "", ""},
+		// the https address is in use.
+		{New().DevCertificate(), "", ""},
+	}
+	inUse, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inUse.Close()
+
+	for i, test := range tests {
+		test.rv.RedirectHTTP(redirectAddr)
+		if err := test.rv.RunTLS(inUse.Addr().String(), test.certFile, test.keyFile); err == nil {
+			t.Errorf("Test %d: expected error", i)
+		}
+		if conn, err := net.Dial("tcp", redirectAddr); err == nil {
+			conn.Close()
+			t.Errorf("Test %d: expected redirect server not to be listening", i)
+		}
+	}
+}
+
+func TestRedirectAfterShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirectAddr := ln.Addr().String()
+	ln.Close()
+
+	rv := New().RedirectHTTP(redirectAddr)
+	rv.Shutdown(context.Background())
+	rv.startRedirect("127.0.0.1:8443")
+	time.Sleep(10 * time.Millisecond)
+	if conn, err := net.Dial("tcp", redirectAddr); err == nil {
+		conn.Close()
+		t.Errorf("expected redirect server not to start after Shutdown")
+	}
+}
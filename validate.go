@@ -0,0 +1,238 @@
+package river
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DependencyError is a handler parameter that cannot be resolved.
+type DependencyError struct {
+	Method  string
+	Path    string
+	Handler string
+	// Param is the index of the parameter.
+	Param int
+	Type  reflect.Type
+	Err   error
+}
+
+func (d *DependencyError) Error() string {
+	return fmt.Sprintf("%s %s %s: parameter %d (%v): %v", d.Method, d.Path, d.Handler, d.Param, d.Type, d.Err)
+}
+
+// ValidationError lists the dependency errors found by Validate.
+type ValidationError []*DependencyError
+
+func (v ValidationError) Error() string {
+	lines := make([]string, len(v))
+	for i := range v {
+		lines[i] = v[i].Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Declare declares services that are registered by middlewares
+// at request time, for Validate.
+//  rv.Declare(river.Principal{})
+func (rv *River) Declare(services ...interface{}) *River {
+	rv.declared = append(rv.declared, typesOf(services)...)
+	return rv
+}
+
+// Optional marks services that handlers may receive as zero values,
+// for Validate.
+//  rv.Optional((*Cache)(nil))
+func (rv *River) Optional(services ...interface{}) *River {
+	rv.optional = append(rv.optional, typesOf(services)...)
+	return rv
+}
+
+// Declare declares services that are registered by middlewares
+// of the endpoint at request time, for Validate.
+//  userEndpoint.Use(authMid)
+//  userEndpoint.Declare(Session{})
+func (e *Endpoint) Declare(services ...interface{}) *Endpoint {
+	e.declared = append(e.declared, typesOf(services)...)
+	return e
+}
+
+// Optional marks services that handlers of the endpoint may
+// receive as zero values, for Validate.
+func (e *Endpoint) Optional(services ...interface{}) *Endpoint {
+	e.optional = append(e.optional, typesOf(services)...)
+	return e
+}
+
+// typesOf returns the types of services. A nil pointer
+// to an interface is the interface type.
+func typesOf(services []interface{}) []reflect.Type {
+	types := make([]reflect.Type, len(services))
+	for i := range services {
+		t := reflect.TypeOf(services[i])
+		if t == nil {
+			panic("river: cannot declare nil service, use a nil pointer e.g. (*Store)(nil)")
+		}
+		if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Interface {
+			t = t.Elem()
+		}
+		types[i] = t
+	}
+	return types
+}
+
+// Validate checks that the parameters of all handlers, and of the providers
+// they depend on, can be resolved from registered, provided, declared or
+// optional services. Run calls Validate before the server starts.
+//
+// The returned error is a ValidationError.
+func (rv *River) Validate() error {
+	var errs ValidationError
+	for _, hp := range rv.handledPaths {
+		t := reflect.TypeOf(hp.h)
+		if t.Kind() != reflect.Func {
+			continue
+		}
+		v := rv.validator(hp.endpoint, t)
+		for i := 0; i < t.NumIn(); i++ {
+			if err := v.check(t.In(i), nil, nil, 0); err != nil {
+				errs = append(errs, &DependencyError{
+					Method:  hp.method,
+					Path:    hp.path,
+					Handler: hp.handler,
+					Param:   i,
+					Type:    t.In(i),
+					Err:     err,
+				})
+			}
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Path != errs[j].Path {
+			return errs[i].Path < errs[j].Path
+		}
+		return errs[i].Method < errs[j].Method
+	})
+	return errs
+}
+
+// declaredService is a placeholder for a service known at request time.
+type declaredService struct {
+	typ reflect.Type
+}
+
+// validator resolves services like a request of a handler of type t.
+type validator struct {
+	// layers are the services of the endpoint, its ancestors
+	// and the River, as resolved at request time.
+	layers []serviceInjector
+	// request are the services known at request time.
+	request  serviceInjector
+	optional map[reflect.Type]bool
+}
+
+func (rv *River) validator(e *Endpoint, t reflect.Type) validator {
+	v := validator{request: make(serviceInjector), optional: make(map[reflect.Type]bool)}
+	for _, s := range rv.layers(e) {
+		v.layers = append(v.layers, *s)
+	}
+
+	declare := func(t reflect.Type, placeholder interface{}) {
+		if _, ok := v.request[t]; !ok {
+			v.request[t] = placeholder
+		}
+	}
+	// Context is also the ResponseWriter.
+	context := &declaredService{reflect.TypeOf(&Context{})}
+	declare(context.typ, context)
+	declare(reflect.TypeOf((*http.ResponseWriter)(nil)).Elem(), context)
+	declare(reflect.TypeOf(&http.Request{}), &declaredService{})
+	declare(reflect.TypeOf(RequestID("")), &declaredService{})
+	for _, b := range bindingsOf(t) {
+		declare(b.typ, &declaredService{b.typ})
+	}
+
+	declared, optional := rv.declared, rv.optional
+	for p := e; p != nil; p = p.parent {
+		declared = append(declared, p.declared...)
+		optional = append(optional, p.optional...)
+	}
+	for _, t := range declared {
+		declare(t, &declaredService{t})
+	}
+	for _, t := range optional {
+		v.optional[t] = true
+	}
+	return v
+}
+
+// find returns the service for t in layers from layer outwards, or in
+// request services, and the layer it is found in.
+func (v validator) find(t reflect.Type, layer int) (interface{}, int, bool) {
+	for i := layer; i < len(v.layers); i++ {
+		if service, ok := v.layers[i][t]; ok {
+			return service, i, true
+		}
+	}
+	service, ok := v.request[t]
+	return service, layer, ok
+}
+
+// check checks that t can be resolved. stack are the types of the
+// providers being checked, singleton is the type of the singleton
+// whose dependencies are checked, if any. Like at request time,
+// services are resolved from layer outwards; a singleton only
+// resolves services of its own layer and outer layers.
+func (v validator) check(t reflect.Type, stack []reflect.Type, singleton reflect.Type, layer int) error {
+	service, found, ok := v.find(t, layer)
+	if !ok && t.Kind() == reflect.Interface {
+		impl, err := implementation(t, append(v.layers[layer:len(v.layers):len(v.layers)], v.request), nil)
+		if err != nil {
+			return err
+		}
+		if impl != nil {
+			return v.check(impl, stack, singleton, layer)
+		}
+	}
+	if !ok {
+		if v.optional[t] {
+			return nil
+		}
+		if singleton != nil && layer > 0 && v.check(t, nil, nil, 0) == nil {
+			return fmt.Errorf("singleton %v cannot depend on %v of an inner endpoint", singleton, t)
+		}
+		return fmt.Errorf("no service registered, provided or declared for %v", t)
+	}
+
+	if _, ok := service.(*declaredService); ok && singleton != nil {
+		return fmt.Errorf("singleton %v cannot depend on request service %v", singleton, t)
+	}
+	p, ok := service.(*provider)
+	if !ok {
+		return nil
+	}
+	if singleton != nil && p.scope != Singleton {
+		return fmt.Errorf("singleton %v cannot depend on %v scoped %v", singleton, p.scope, t)
+	}
+	if p.scope == Singleton {
+		singleton, layer = t, found
+	}
+	for i := range stack {
+		if stack[i] == t {
+			return cycleError(append(stack[i:len(stack):len(stack)], t))
+		}
+	}
+	stack = append(stack, t)
+	ft := reflect.TypeOf(p.factory)
+	for i := 0; i < ft.NumIn(); i++ {
+		if err := v.check(ft.In(i), stack, singleton, layer); err != nil {
+			return fmt.Errorf("provider of %v: %v", t, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package river
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type validateDB struct{}
+
+type validateTx struct{}
+
+type validateCache struct{}
+
+type validateSession struct{}
+
+type validateRequest struct {
+	ID string `river:"path=id"`
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		setup    func(rv *River, e *Endpoint)
+		handler  Handler
+		expected []string
+	}{
+		{func(rv *River, e *Endpoint) {}, func(c *Context, r RequestID, req validateRequest) {}, nil},
+		{func(rv *River, e *Endpoint) {}, func(db *validateDB) {}, []string{"parameter 0 (*river.validateDB): no service"}},
+		{func(rv *River, e *Endpoint) { rv.Register(&validateDB{}) }, func(db *validateDB) {}, nil},
+		{func(rv *River, e *Endpoint) { e.Declare(validateSession{}) }, func(s validateSession) {}, nil},
+		{func(rv *River, e *Endpoint) { rv.Optional((*validateCache)(nil)) }, func(c *validateCache) {}, nil},
+		{func(rv *River, e *Endpoint) {
+			e.Provide(func(db *validateDB) *validateTx { return nil }, RequestScope)
+		}, func(tx *validateTx) {}, []string{"provider of *river.validateTx: no service", "*river.validateDB"}},
+		{func(rv *River, e *Endpoint) {
+			e.Provide(func(db *validateDB) *validateTx { return nil }, RequestScope)
+			e.Provide(func(tx *validateTx) *validateDB { return nil }, Transient)
+		}, func(tx *validateTx) {}, []string{"dependency cycle *river.validateTx -> *river.validateDB -> *river.validateTx"}},
+		{func(rv *River, e *Endpoint) {
+			e.Provide(func(tx *validateTx) *validateDB { return nil }, Singleton)
+			e.Provide(func() *validateTx { return nil }, RequestScope)
+		}, func(db *validateDB) {}, []string{"singleton *river.validateDB cannot depend on request scoped *river.validateTx"}},
+		{func(rv *River, e *Endpoint) {
+			e.Provide(func(c *Context) *validateDB { return nil }, Singleton)
+		}, func(db *validateDB) {}, []string{"singleton *river.validateDB cannot depend on request service *river.Context"}},
+		{func(rv *River, e *Endpoint) { rv.Register(&memStore{}) }, func(s store) {}, nil},
+		{func(rv *River, e *Endpoint) {
+			rv.Register(&memStore{})
+			rv.Register(fileStore{})
+		}, func(s store) {}, []string{"ambiguous service river.store"}},
+		{func(rv *River, e *Endpoint) {}, func(w interface{ Write([]byte) (int, error) }) {}, nil},
+		// singletons resolve services of their own and outer layers.
+		{func(rv *River, e *Endpoint) {
+			rv.Provide(func(tx *validateTx) *validateDB { return nil }, Singleton)
+			e.Register(&validateTx{})
+		}, func(db *validateDB) {}, []string{"singleton *river.validateDB cannot depend on *river.validateTx of an inner endpoint"}},
+		{func(rv *River, e *Endpoint) {
+			e.Provide(func(tx *validateTx) *validateDB { return nil }, Singleton)
+			rv.Register(&validateTx{})
+		}, func(db *validateDB) {}, nil},
+		{func(rv *River, e *Endpoint) {
+			rv.Provide(func(s store) *validateDB { return nil }, Singleton)
+			e.Register(&memStore{})
+		}, func(db *validateDB) {}, []string{"cannot depend on river.store of an inner endpoint"}},
+	}
+	for i, test := range tests {
+		rv := New()
+		e := NewEndpoint().Get("/", test.handler)
+		test.setup(rv, e)
+		rv.Handle("/v", e)
+
+		err := rv.Validate()
+		if len(test.expected) == 0 {
+			if err != nil {
+				t.Errorf("Test %d: expected no error, found %v", i, err)
+			}
+			continue
+		}
+		var verr ValidationError
+		if !errors.As(err, &verr) || len(verr) != 1 {
+			t.Errorf("Test %d: expected 1 dependency error, found %v", i, err)
+			continue
+		}
+		if verr[0].Method != "GET" || verr[0].Path != "/v" || verr[0].Type != reflect.TypeOf(test.handler).In(0) {
+			t.Errorf("Test %d: unexpected dependency error %+v", i, verr[0])
+		}
+		for _, s := range test.expected {
+			if !strings.Contains(err.Error(), s) {
+				t.Errorf("Test %d: expected error to contain %q, found %q", i, s, err)
+			}
+		}
+	}
+}
+
+func TestRunValidates(t *testing.T) {
+	rv := New().Handle("/", NewEndpoint().Get("/", func(db *validateDB) {}))
+	var verr ValidationError
+	if err := rv.Run("127.0.0.1:0"); !errors.As(err, &verr) {
+		t.Errorf("Expected ValidationError from Run, found %v", err)
+	}
+}
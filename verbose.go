@@ -2,37 +2,128 @@ package river
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"reflect"
 	"runtime"
+	"sort"
+	"strings"
 )
 
 type verbose struct {
 	handledPaths handledPaths
 }
 
+// Route is a route handled by a River.
+type Route struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Handler string `json:"handler"`
+	// Middlewares are the endpoint middlewares, including
+	// inherited ones. Global middlewares are not included.
+	Middlewares []string `json:"middlewares,omitempty"`
+	Renderer    string   `json:"renderer"`
+	// Params are the types of the handler parameters.
+	Params []string `json:"params,omitempty"`
+}
+
+// DumpFormat is the output format of DumpTo.
+type DumpFormat int
+
+// Dump formats.
+const (
+	DumpTable DumpFormat = iota
+	DumpJSON
+)
+
+// Routes returns all handled routes sorted by path and method.
+func (rv *River) Routes() []Route {
+	routes := make([]Route, 0, len(rv.handledPaths))
+	for _, hp := range rv.handledPaths {
+		route := Route{
+			Method:   hp.method,
+			Path:     hp.path,
+			Handler:  hp.handler,
+			Renderer: nameOf(notNilRenderer(rv.renderer)),
+		}
+		if hp.endpoint != nil {
+			for _, m := range hp.endpoint.middlewares() {
+				route.Middlewares = append(route.Middlewares, nameOf(m))
+			}
+			route.Renderer = nameOf(notNilRenderer(hp.endpoint.inheritedRenderer(), rv.renderer))
+		}
+		if t := reflect.TypeOf(hp.h); t != nil && t.Kind() == reflect.Func {
+			for i := 0; i < t.NumIn(); i++ {
+				route.Params = append(route.Params, t.In(i).String())
+			}
+		}
+		routes = append(routes, route)
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
 // Dump dumps all endpoints that are being handled to the log.
-func (v verbose) Dump() {
+func (rv *River) Dump() {
 	var b bytes.Buffer
 	fmt.Fprintln(&b)
+	rv.DumpTo(&b, DumpTable)
+	log.println(b.String())
+}
+
+// DumpTo writes all routes that are being handled to w in format.
+func (rv *River) DumpTo(w io.Writer, format DumpFormat) error {
+	routes := rv.Routes()
+	if format == DumpJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(routes)
+	}
+
+	var b bytes.Buffer
 	fmt.Fprintln(&b, "Endpoints")
 	fmt.Fprintln(&b, "---------")
-	for _, hp := range v.handledPaths {
-		fmt.Fprintf(&b, "%-8s  %-25s  %s\n", hp.method, hp.path, hp.handler)
+	for _, r := range routes {
+		fmt.Fprintf(&b, "%-8s  %-25s  %s", r.Method, r.Path, r.Handler)
+		if len(r.Middlewares) > 0 {
+			fmt.Fprintf(&b, "  [%s]", strings.Join(r.Middlewares, ", "))
+		}
+		fmt.Fprintln(&b)
 	}
-	log.println(b.String())
+	_, err := b.WriteTo(w)
+	return err
 }
 
 type handledPath struct {
 	path, method, handler string
+	h                     Handler
+	endpoint              *Endpoint
+	subpath               string
 }
 
 type handledPaths []handledPath
 
-func (h *handledPaths) add(method, path string, handler string) {
-	*h = append(*h, handledPath{path: path, method: method, handler: handler})
+func (h *handledPaths) add(method, path, subpath string, handler Handler, e *Endpoint) {
+	*h = append(*h, handledPath{
+		path:     path,
+		method:   method,
+		handler:  nameOf(handler),
+		h:        handler,
+		endpoint: e,
+		subpath:  subpath,
+	})
 }
 
 func nameOf(f interface{}) string {
-	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func {
+		return reflect.TypeOf(f).String()
+	}
+	return runtime.FuncForPC(v.Pointer()).Name()
 }
@@ -0,0 +1,57 @@
+package river
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testMiddleware(c *Context) { c.Next() }
+
+func TestRiver_Routes(t *testing.T) {
+	type Model struct{}
+	e := NewEndpoint().
+		Get("/:id", func(c *Context, m Model) {}).
+		Post("/", func() {})
+	e.Use(testMiddleware)
+	e.Renderer(PlainRenderer)
+	rv := New().Handle("/user", e)
+
+	routes := rv.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, found %d", len(routes))
+	}
+	expected := Route{
+		Method:      "POST",
+		Path:        "/user",
+		Handler:     "github.com/abiosoft/river.TestRiver_Routes.func2",
+		Middlewares: []string{"github.com/abiosoft/river.testMiddleware"},
+		Renderer:    "github.com/abiosoft/river.PlainRenderer",
+	}
+	if !reflect.DeepEqual(routes[0], expected) {
+		t.Errorf("expected %+v, found %+v", expected, routes[0])
+	}
+	if params := strings.Join(routes[1].Params, ","); params != "*river.Context,river.Model" {
+		t.Errorf("unexpected params %s", params)
+	}
+
+	var b bytes.Buffer
+	if err := rv.DumpTo(&b, DumpJSON); err != nil {
+		t.Fatal(err)
+	}
+	var dumped []Route
+	if err := json.Unmarshal(b.Bytes(), &dumped); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dumped, routes) {
+		t.Errorf("expected %+v, found %+v", routes, dumped)
+	}
+
+	b.Reset()
+	rv.DumpTo(&b, DumpTable)
+	if !strings.Contains(b.String(), "POST      /user") {
+		t.Errorf("unexpected table %s", b.String())
+	}
+}